@@ -68,6 +68,73 @@ func (ls *LetStatement) String() string { // print the AST
 	return out.String()
 }
 
+// MultiLetStatement binds several names at once: `let a, b = 1, 2;`. All values are evaluated before any name is
+// bound, and the number of names and values must match.
+
+type MultiLetStatement struct {
+	Token  token.Token // the token.LET token
+	Names  []*Identifier
+	Values []Expression
+}
+
+func (ms *MultiLetStatement) statementNode()       {}
+func (ms *MultiLetStatement) TokenLiteral() string { return ms.Token.Literal }
+func (ms *MultiLetStatement) String() string {
+	var out bytes.Buffer
+
+	names := []string{}
+	for _, n := range ms.Names {
+		names = append(names, n.String())
+	}
+
+	values := []string{}
+	for _, v := range ms.Values {
+		if v != nil {
+			values = append(values, v.String())
+		}
+	}
+
+	out.WriteString(ms.TokenLiteral() + " ")
+	out.WriteString(strings.Join(names, ", "))
+	out.WriteString(" = ")
+	out.WriteString(strings.Join(values, ", "))
+	out.WriteString(";")
+	return out.String()
+}
+
+// MultiAssignStatement assigns new values to several existing bindings at once: `a, b = b, a;`. Like
+// MultiLetStatement, all values are evaluated first, which is what makes the swap idiom work.
+
+type MultiAssignStatement struct {
+	Token   token.Token // the first target's token.IDENT token
+	Targets []*Identifier
+	Values  []Expression
+}
+
+func (ms *MultiAssignStatement) statementNode()       {}
+func (ms *MultiAssignStatement) TokenLiteral() string { return ms.Token.Literal }
+func (ms *MultiAssignStatement) String() string {
+	var out bytes.Buffer
+
+	targets := []string{}
+	for _, t := range ms.Targets {
+		targets = append(targets, t.String())
+	}
+
+	values := []string{}
+	for _, v := range ms.Values {
+		if v != nil {
+			values = append(values, v.String())
+		}
+	}
+
+	out.WriteString(strings.Join(targets, ", "))
+	out.WriteString(" = ")
+	out.WriteString(strings.Join(values, ", "))
+	out.WriteString(";")
+	return out.String()
+}
+
 type Identifier struct {
 	Token token.Token // the token.IDENT token
 	Value string      // the value of the identifier
@@ -86,9 +153,9 @@ func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 func (rs *ReturnStatement) String() string { // print the AST
 	var out bytes.Buffer
-	out.WriteString(rs.TokenLiteral() + " ")
+	out.WriteString(rs.TokenLiteral())
 	if rs.ReturnValue != nil {
-		out.WriteString(rs.ReturnValue.String())
+		out.WriteString(" " + rs.ReturnValue.String())
 	}
 	out.WriteString(";")
 	return out.String()
@@ -263,3 +330,51 @@ func (ce *CallExpression) String() string {
 
 	return out.String()
 }
+
+// DecoratorExpression is a function literal preceded by a decorator, e.g. `@memoize fn(n) { ... }` or, for decorators
+// that take arguments, `@retry(3) fn() { ... }`. Evaluating it calls the decorator with the function and yields
+// whatever the decorator returns. Decorators can be stacked, in which case Function is another DecoratorExpression.
+
+type DecoratorExpression struct {
+	Token     token.Token // the '@' token
+	Decorator Expression  // the Identifier or CallExpression after the '@'
+	Function  Expression  // the decorated FunctionLiteral or DecoratorExpression
+}
+
+func (de *DecoratorExpression) expressionNode()      {}
+func (de *DecoratorExpression) TokenLiteral() string { return de.Token.Literal }
+func (de *DecoratorExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("@")
+	out.WriteString(de.Decorator.String())
+	out.WriteString(" ")
+	out.WriteString(de.Function.String())
+
+	return out.String()
+}
+
+// LetInExpression binds a name for the duration of a single expression: `let x = 5 in x + 1`. The name is only visible
+// in Body, which is evaluated in a new environment enclosed by the current one.
+
+type LetInExpression struct {
+	Token token.Token // the token.LET token
+	Name  *Identifier
+	Value Expression
+	Body  Expression
+}
+
+func (le *LetInExpression) expressionNode()      {}
+func (le *LetInExpression) TokenLiteral() string { return le.Token.Literal }
+func (le *LetInExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(le.TokenLiteral() + " ")
+	out.WriteString(le.Name.String())
+	out.WriteString(" = ")
+	out.WriteString(le.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(le.Body.String())
+
+	return out.String()
+}
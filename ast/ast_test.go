@@ -1,6 +1,7 @@
 package ast
 
 import (
+	"fmt"
 	"monkey/token"
 	"testing"
 )
@@ -26,3 +27,90 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. Got %q", program.String())
 	}
 }
+
+func TestStringWithMissingValues(t *testing.T) {
+	// statements the parser gave up on halfway can be left with nil values
+	a := &Identifier{Token: token.Token{Type: token.IDENT, Literal: "a"}, Value: "a"}
+	one := &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "1"}, Value: 1}
+	program := &Program{
+		Statements: []Statement{
+			&MultiLetStatement{Token: token.Token{Type: token.LET, Literal: "let"}, Names: []*Identifier{a, a},
+				Values: []Expression{one, nil}},
+			&MultiAssignStatement{Token: a.Token, Targets: []*Identifier{a, a}, Values: []Expression{nil, one}},
+		},
+	}
+
+	if program.String() != "let a, a = 1;a, a = 1;" {
+		t.Errorf("program.String() wrong. Got %q", program.String())
+	}
+}
+
+func TestInspect(t *testing.T) {
+	// let f = fn(x) { if (x) { x } };
+	x := &Identifier{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"}
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let"},
+				Name:  &Identifier{Token: token.Token{Type: token.IDENT, Literal: "f"}, Value: "f"},
+				Value: &FunctionLiteral{
+					Token:      token.Token{Type: token.FUNCTION, Literal: "fn"},
+					Parameters: []*Identifier{x},
+					Body: &BlockStatement{
+						Token: token.Token{Type: token.LBRACE, Literal: "{"},
+						Statements: []Statement{
+							&ExpressionStatement{
+								Token: token.Token{Type: token.IF, Literal: "if"},
+								Expression: &IfExpression{
+									Token:     token.Token{Type: token.IF, Literal: "if"},
+									Condition: x,
+									Consequence: &BlockStatement{
+										Token:      token.Token{Type: token.LBRACE, Literal: "{"},
+										Statements: []Statement{&ExpressionStatement{Token: x.Token, Expression: x}},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var visited []string
+	depth, maxDepth := 0, 0
+	Inspect(program, func(node Node) bool {
+		if node == nil {
+			depth--
+			return true
+		}
+		depth++
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		visited = append(visited, fmt.Sprintf("%T", node))
+		return true
+	})
+
+	expected := []string{
+		"*ast.Program", "*ast.LetStatement", "*ast.Identifier", "*ast.FunctionLiteral", "*ast.Identifier",
+		"*ast.BlockStatement", "*ast.ExpressionStatement", "*ast.IfExpression", "*ast.Identifier",
+		"*ast.BlockStatement", "*ast.ExpressionStatement", "*ast.Identifier",
+	}
+
+	if len(visited) != len(expected) {
+		t.Fatalf("wrong number of nodes visited. want=%d, got=%d (%v)", len(expected), len(visited), visited)
+	}
+	for i, typ := range expected {
+		if visited[i] != typ {
+			t.Errorf("visited[%d] wrong. want=%s, got=%s", i, typ, visited[i])
+		}
+	}
+
+	if depth != 0 {
+		t.Errorf("f(nil) calls don't balance the visits. depth=%d", depth)
+	}
+	if maxDepth != 9 {
+		t.Errorf("wrong max depth. want=9, got=%d", maxDepth)
+	}
+}
@@ -0,0 +1,147 @@
+package ast
+
+import "reflect"
+
+// Clone() returns a deep copy of node: tokens are copied by value and every child node is cloned in turn, so a pass
+// can rewrite the copy without the original noticing. Nil children, including typed nil pointers like the missing
+// Alternative of an if, stay nil.
+
+func Clone(node Node) Node {
+	if isNil(node) {
+		return node
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		return &Program{Statements: cloneStatements(node.Statements)}
+	case *LetStatement:
+		return &LetStatement{Token: node.Token, Name: cloneIdentifier(node.Name), Value: cloneExpression(node.Value)}
+	case *MultiLetStatement:
+		return &MultiLetStatement{
+			Token:  node.Token,
+			Names:  cloneIdentifiers(node.Names),
+			Values: cloneExpressions(node.Values),
+		}
+	case *MultiAssignStatement:
+		return &MultiAssignStatement{
+			Token:   node.Token,
+			Targets: cloneIdentifiers(node.Targets),
+			Values:  cloneExpressions(node.Values),
+		}
+	case *ReturnStatement:
+		return &ReturnStatement{Token: node.Token, ReturnValue: cloneExpression(node.ReturnValue)}
+	case *ExpressionStatement:
+		return &ExpressionStatement{Token: node.Token, Expression: cloneExpression(node.Expression)}
+	case *BlockStatement:
+		return cloneBlock(node)
+	case *Identifier:
+		return cloneIdentifier(node)
+	case *IntegerLiteral:
+		return &IntegerLiteral{Token: node.Token, Value: node.Value}
+	case *Boolean:
+		return &Boolean{Token: node.Token, Value: node.Value}
+	case *PrefixExpression:
+		return &PrefixExpression{Token: node.Token, Operator: node.Operator, Right: cloneExpression(node.Right)}
+	case *InfixExpression:
+		return &InfixExpression{
+			Token:    node.Token,
+			Left:     cloneExpression(node.Left),
+			Operator: node.Operator,
+			Right:    cloneExpression(node.Right),
+		}
+	case *IfExpression:
+		return &IfExpression{
+			Token:       node.Token,
+			Condition:   cloneExpression(node.Condition),
+			Consequence: cloneBlock(node.Consequence),
+			Alternative: cloneBlock(node.Alternative),
+		}
+	case *FunctionLiteral:
+		return &FunctionLiteral{
+			Token:      node.Token,
+			Parameters: cloneIdentifiers(node.Parameters),
+			Body:       cloneBlock(node.Body),
+		}
+	case *CallExpression:
+		return &CallExpression{
+			Token:     node.Token,
+			Function:  cloneExpression(node.Function),
+			Arguments: cloneExpressions(node.Arguments),
+		}
+	case *DecoratorExpression:
+		return &DecoratorExpression{
+			Token:     node.Token,
+			Decorator: cloneExpression(node.Decorator),
+			Function:  cloneExpression(node.Function),
+		}
+	case *LetInExpression:
+		return &LetInExpression{
+			Token: node.Token,
+			Name:  cloneIdentifier(node.Name),
+			Value: cloneExpression(node.Value),
+			Body:  cloneExpression(node.Body),
+		}
+	}
+
+	panic("ast.Clone: unknown node type " + reflect.TypeOf(node).String())
+}
+
+// Equal() reports whether a and b are structurally the same tree, tokens included.
+
+func Equal(a, b Node) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func cloneStatements(statements []Statement) []Statement {
+	if statements == nil {
+		return nil
+	}
+	cloned := make([]Statement, len(statements))
+	for i, s := range statements {
+		cloned[i], _ = Clone(s).(Statement)
+	}
+	return cloned
+}
+
+func cloneExpressions(expressions []Expression) []Expression {
+	if expressions == nil {
+		return nil
+	}
+	cloned := make([]Expression, len(expressions))
+	for i, e := range expressions {
+		cloned[i] = cloneExpression(e)
+	}
+	return cloned
+}
+
+func cloneIdentifiers(identifiers []*Identifier) []*Identifier {
+	if identifiers == nil {
+		return nil
+	}
+	cloned := make([]*Identifier, len(identifiers))
+	for i, ident := range identifiers {
+		cloned[i] = cloneIdentifier(ident)
+	}
+	return cloned
+}
+
+func cloneExpression(e Expression) Expression {
+	if isNil(e) {
+		return e
+	}
+	return Clone(e).(Expression)
+}
+
+func cloneIdentifier(ident *Identifier) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	return &Identifier{Token: ident.Token, Value: ident.Value}
+}
+
+func cloneBlock(block *BlockStatement) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	return &BlockStatement{Token: block.Token, Statements: cloneStatements(block.Statements)}
+}
@@ -0,0 +1,59 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	input := `
+let a, b = 1, 2;
+a, b = b, a;
+let add = fn(x, y) { return x + y; };
+let r = if (a < b) { -add(a, 10) } else { !true };
+if (a) { 1 };
+@memo fn(n) { n };
+let s = let t = 3 in t * a;
+`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	original := program.String()
+	clone := ast.Clone(program).(*ast.Program)
+
+	if !ast.Equal(program, clone) {
+		t.Fatalf("clone is not equal to the original")
+	}
+	if clone == program || clone.Statements[0] == program.Statements[0] {
+		t.Fatalf("clone shares nodes with the original")
+	}
+
+	ast.Inspect(clone, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.IntegerLiteral:
+			node.Value *= 100
+			node.Token.Literal += "00"
+		case *ast.Identifier:
+			node.Value = "_" + node.Value
+		case *ast.Boolean:
+			node.Value = !node.Value
+		}
+		return true
+	})
+
+	if program.String() != original {
+		t.Errorf("original changed after mutating the clone.\nwant=%q\ngot= %q", original, program.String())
+	}
+	if ast.Equal(program, clone) {
+		t.Errorf("mutated clone is still equal to the original")
+	}
+	if clone.String() == original {
+		t.Errorf("mutating the clone had no effect")
+	}
+}
@@ -0,0 +1,46 @@
+package ast
+
+// IdentRef is a single occurrence of an identifier in a program, for tooling like go-to-definition and rename.
+// Line and Column are those of the identifier's token, so they're 0 for trees that weren't built by the parser.
+
+type IdentRef struct {
+	Name         string
+	Line         int
+	Column       int
+	IsDefinition bool // the name of a let or let-in, or a function parameter; every other occurrence is a use
+}
+
+// Identifiers() returns every identifier occurrence in program, in source order. Assignment targets like the `a` in
+// `a, b = b, a` count as uses: they refer to a binding defined elsewhere.
+
+func Identifiers(program *Program) []IdentRef {
+	definitions := map[*Identifier]bool{}
+	refs := []IdentRef{}
+
+	Inspect(program, func(node Node) bool {
+		switch node := node.(type) {
+		case *LetStatement:
+			definitions[node.Name] = true
+		case *MultiLetStatement:
+			for _, name := range node.Names {
+				definitions[name] = true
+			}
+		case *LetInExpression:
+			definitions[node.Name] = true
+		case *FunctionLiteral:
+			for _, p := range node.Parameters {
+				definitions[p] = true
+			}
+		case *Identifier:
+			refs = append(refs, IdentRef{
+				Name:         node.Value,
+				Line:         node.Token.Line,
+				Column:       node.Token.Column,
+				IsDefinition: definitions[node],
+			})
+		}
+		return true
+	})
+
+	return refs
+}
@@ -0,0 +1,49 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func TestIdentifiers(t *testing.T) {
+	input := `let add = fn(x, y) {
+  x + y
+};
+a, b = b, add(a, 1);
+let z = let w = 2 in w;`
+
+	expected := []ast.IdentRef{
+		{Name: "add", Line: 1, Column: 5, IsDefinition: true},
+		{Name: "x", Line: 1, Column: 14, IsDefinition: true},
+		{Name: "y", Line: 1, Column: 17, IsDefinition: true},
+		{Name: "x", Line: 2, Column: 3},
+		{Name: "y", Line: 2, Column: 7},
+		{Name: "a", Line: 4, Column: 1},
+		{Name: "b", Line: 4, Column: 4},
+		{Name: "b", Line: 4, Column: 8},
+		{Name: "add", Line: 4, Column: 11},
+		{Name: "a", Line: 4, Column: 15},
+		{Name: "z", Line: 5, Column: 5, IsDefinition: true},
+		{Name: "w", Line: 5, Column: 13, IsDefinition: true},
+		{Name: "w", Line: 5, Column: 22},
+	}
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	refs := ast.Identifiers(program)
+	if len(refs) != len(expected) {
+		t.Fatalf("wrong number of identifiers. want=%d, got=%d (%+v)", len(expected), len(refs), refs)
+	}
+	for i, ref := range refs {
+		if ref != expected[i] {
+			t.Errorf("refs[%d] wrong. want=%+v, got=%+v", i, expected[i], ref)
+		}
+	}
+}
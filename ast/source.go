@@ -0,0 +1,127 @@
+package ast
+
+import "strings"
+
+// Source() prints node as Monkey source that parses back into the same tree. String() is meant for reading and leaves
+// out the braces around blocks, so `fn(x) { if (x) { return 1; } 2 }` comes out of it as `fn(x)ifx return 1;2`, which
+// doesn't parse. Source() keeps them, and puts a `;` after every statement:
+//
+//	fn(x) { if (x) { return 1; }; 2; }
+//
+// Operators are fully parenthesized, the same way String() does it.
+
+func Source(node Node) string {
+	var out strings.Builder
+	writeSource(&out, node)
+	return out.String()
+}
+
+func writeSource(out *strings.Builder, node Node) {
+	if isNil(node) {
+		return
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		for i, s := range node.Statements {
+			if i > 0 {
+				out.WriteString(" ")
+			}
+			writeSource(out, s)
+		}
+	case *LetStatement:
+		out.WriteString("let " + node.Name.Value + " = ")
+		writeSource(out, node.Value)
+		out.WriteString(";")
+	case *MultiLetStatement:
+		out.WriteString("let ")
+		writeIdentifierList(out, node.Names)
+		out.WriteString(" = ")
+		writeSourceList(out, node.Values)
+		out.WriteString(";")
+	case *MultiAssignStatement:
+		writeIdentifierList(out, node.Targets)
+		out.WriteString(" = ")
+		writeSourceList(out, node.Values)
+		out.WriteString(";")
+	case *ReturnStatement:
+		out.WriteString("return")
+		if !isNil(node.ReturnValue) {
+			out.WriteString(" ")
+			writeSource(out, node.ReturnValue)
+		}
+		out.WriteString(";")
+	case *ExpressionStatement:
+		writeSource(out, node.Expression)
+		out.WriteString(";")
+	case *BlockStatement:
+		out.WriteString("{ ")
+		for _, s := range node.Statements {
+			writeSource(out, s)
+			out.WriteString(" ")
+		}
+		out.WriteString("}")
+	case *IfExpression:
+		out.WriteString("if (")
+		writeSource(out, node.Condition)
+		out.WriteString(") ")
+		writeSource(out, node.Consequence)
+		if node.Alternative != nil {
+			out.WriteString(" else ")
+			writeSource(out, node.Alternative)
+		}
+	case *FunctionLiteral:
+		out.WriteString("fn(")
+		writeIdentifierList(out, node.Parameters)
+		out.WriteString(") ")
+		writeSource(out, node.Body)
+	case *PrefixExpression:
+		out.WriteString("(" + node.Operator)
+		writeSource(out, node.Right)
+		out.WriteString(")")
+	case *InfixExpression:
+		out.WriteString("(")
+		writeSource(out, node.Left)
+		out.WriteString(" " + node.Operator + " ")
+		writeSource(out, node.Right)
+		out.WriteString(")")
+	case *CallExpression:
+		writeSource(out, node.Function)
+		out.WriteString("(")
+		writeSourceList(out, node.Arguments)
+		out.WriteString(")")
+	case *DecoratorExpression:
+		out.WriteString("@")
+		writeSource(out, node.Decorator)
+		out.WriteString(" ")
+		writeSource(out, node.Function)
+	case *LetInExpression:
+		// the body of a let-in reaches as far right as it can, so it's parenthesized to keep it from swallowing
+		// whatever follows it
+		out.WriteString("(let " + node.Name.Value + " = ")
+		writeSource(out, node.Value)
+		out.WriteString(" in ")
+		writeSource(out, node.Body)
+		out.WriteString(")")
+	default: // identifiers and literals
+		out.WriteString(node.String())
+	}
+}
+
+func writeSourceList(out *strings.Builder, expressions []Expression) {
+	for i, e := range expressions {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		writeSource(out, e)
+	}
+}
+
+func writeIdentifierList(out *strings.Builder, identifiers []*Identifier) {
+	for i, ident := range identifiers {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(ident.Value)
+	}
+}
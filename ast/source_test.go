@@ -0,0 +1,53 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5", "let x = 5;"},
+		{"let a, b = 1, 2; a, b = b, a", "let a, b = 1, 2; a, b = b, a;"},
+		{"-a * b", "((-a) * b);"},
+		{"return;", "return;"},
+		{
+			"let f = fn(n) { if (n > 1) { return n; } else { 0 } };",
+			"let f = fn(n) { if ((n > 1)) { return n; } else { 0; }; };",
+		},
+		{"if (x) { 1 }", "if (x) { 1; };"},
+		{"fn() {}", "fn() { };"},
+		{"@memo @retry(3) fn(x) { x }", "@memo @retry(3) fn(x) { x; };"},
+		{"1 + let x = 2 in x * 3", "(1 + (let x = 2 in (x * 3)));"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+
+		source := ast.Source(program)
+		if source != tt.expected {
+			t.Errorf("Source() wrong for %q.\nwant=%q\ngot= %q", tt.input, tt.expected, source)
+		}
+
+		// the printed source has to parse back into the same program
+		if reparsed := parse(t, source); reparsed.String() != program.String() {
+			t.Errorf("source for %q doesn't parse back into the same program.\nwant=%q\ngot= %q",
+				tt.input, program.String(), reparsed.String())
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
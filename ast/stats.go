@@ -0,0 +1,70 @@
+package ast
+
+// Stats summarizes a program for tooling: how many nodes of each kind it contains and how deeply its blocks nest.
+
+type Stats struct {
+	Nodes                int // every node in the tree, including the program itself
+	Statements           int // top-level statements only
+	LetStatements        int // including multiple lets
+	ReturnStatements     int
+	ExpressionStatements int
+	Identifiers          int // every identifier occurrence: names, parameters and references
+	IntegerLiterals      int
+	FunctionLiterals     int
+	CallExpressions      int
+	IfExpressions        int
+	MaxBlockDepth        int // 0 if there are no blocks, 1 for a function body, 2 for an if inside it, ...
+}
+
+// ComputeStats() collects the Stats for program in a single traversal.
+
+func ComputeStats(program *Program) Stats {
+	stats := Stats{Statements: len(program.Statements)}
+
+	var blocks []bool // for each node on the path from the root: is it a block?
+	depth := 0
+
+	Inspect(program, func(node Node) bool {
+		if node == nil { // leaving the node on top of the path
+			if blocks[len(blocks)-1] {
+				depth--
+			}
+			blocks = blocks[:len(blocks)-1]
+			return true
+		}
+
+		stats.Nodes++
+
+		_, isBlock := node.(*BlockStatement)
+		blocks = append(blocks, isBlock)
+		if isBlock {
+			depth++
+			if depth > stats.MaxBlockDepth {
+				stats.MaxBlockDepth = depth
+			}
+		}
+
+		switch node.(type) {
+		case *LetStatement, *MultiLetStatement:
+			stats.LetStatements++
+		case *ReturnStatement:
+			stats.ReturnStatements++
+		case *ExpressionStatement:
+			stats.ExpressionStatements++
+		case *Identifier:
+			stats.Identifiers++
+		case *IntegerLiteral:
+			stats.IntegerLiterals++
+		case *FunctionLiteral:
+			stats.FunctionLiterals++
+		case *CallExpression:
+			stats.CallExpressions++
+		case *IfExpression:
+			stats.IfExpressions++
+		}
+
+		return true
+	})
+
+	return stats
+}
@@ -0,0 +1,56 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func TestComputeStats(t *testing.T) {
+	input := `
+let max = fn(a, b) {
+	if (a > b) {
+		return a;
+	}
+	b;
+};
+let apply = fn(f, x) { f(x) };
+let a, b = 1, 2;
+apply(fn(x) { if (x) { if (x > 1) { x } } }, max(a, b));
+`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	stats := ast.ComputeStats(program)
+
+	tests := []struct {
+		name     string
+		got      int
+		expected int
+	}{
+		{"Statements", stats.Statements, 4},
+		{"LetStatements", stats.LetStatements, 3},
+		{"ReturnStatements", stats.ReturnStatements, 1},
+		{"FunctionLiterals", stats.FunctionLiterals, 3},
+		{"CallExpressions", stats.CallExpressions, 3},
+		{"IfExpressions", stats.IfExpressions, 3},
+		{"IntegerLiterals", stats.IntegerLiterals, 3},
+		{"MaxBlockDepth", stats.MaxBlockDepth, 3},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.expected {
+			t.Errorf("stats.%s wrong. want=%d, got=%d", tt.name, tt.expected, tt.got)
+		}
+	}
+
+	if empty := ast.ComputeStats(&ast.Program{}); empty != (ast.Stats{Nodes: 1}) {
+		t.Errorf("stats for an empty program wrong. got=%+v", empty)
+	}
+}
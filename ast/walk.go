@@ -0,0 +1,94 @@
+package ast
+
+import "reflect"
+
+// Inspect() traverses the AST in depth-first order, the same way go/ast.Inspect does: it calls f(node) and, if f
+// returns true, inspects each of node's non-nil children and then calls f(nil). The trailing f(nil) lets callers keep
+// track of how deep they are in the tree.
+
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+
+	for _, child := range children(node) {
+		Inspect(child, f)
+	}
+
+	f(nil)
+}
+
+func children(node Node) []Node {
+	var nodes []Node
+	add := func(children ...Node) {
+		for _, c := range children {
+			if !isNil(c) {
+				nodes = append(nodes, c)
+			}
+		}
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		for _, s := range node.Statements {
+			add(s)
+		}
+	case *LetStatement:
+		add(node.Name, node.Value)
+	case *MultiLetStatement:
+		for _, n := range node.Names {
+			add(n)
+		}
+		for _, v := range node.Values {
+			add(v)
+		}
+	case *MultiAssignStatement:
+		for _, t := range node.Targets {
+			add(t)
+		}
+		for _, v := range node.Values {
+			add(v)
+		}
+	case *ReturnStatement:
+		add(node.ReturnValue)
+	case *ExpressionStatement:
+		add(node.Expression)
+	case *BlockStatement:
+		for _, s := range node.Statements {
+			add(s)
+		}
+	case *PrefixExpression:
+		add(node.Right)
+	case *InfixExpression:
+		add(node.Left, node.Right)
+	case *IfExpression:
+		add(node.Condition, node.Consequence, node.Alternative)
+	case *FunctionLiteral:
+		for _, p := range node.Parameters {
+			add(p)
+		}
+		add(node.Body)
+	case *CallExpression:
+		add(node.Function)
+		for _, a := range node.Arguments {
+			add(a)
+		}
+	case *DecoratorExpression:
+		add(node.Decorator, node.Function)
+	case *LetInExpression:
+		add(node.Name, node.Value, node.Body)
+	}
+
+	return nodes
+}
+
+// isNil() also catches typed nil pointers stored in an interface, e.g. the nil *BlockStatement the parser leaves as
+// the Alternative of an if without an else.
+
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
@@ -0,0 +1,137 @@
+// Package astbuilder builds AST nodes with correctly filled-in tokens, so tests can construct trees in one line
+// instead of spelling out every token.Token by hand. The nodes are identical to what the parser produces for the
+// equivalent source.
+package astbuilder
+
+import (
+	"monkey/ast"
+	"monkey/token"
+	"strconv"
+)
+
+func Program(statements ...ast.Statement) *ast.Program {
+	return &ast.Program{Statements: append([]ast.Statement{}, statements...)}
+}
+
+func Let(name string, value ast.Expression) *ast.LetStatement {
+	return &ast.LetStatement{
+		Token: token.Token{Type: token.LET, Literal: "let"},
+		Name:  Ident(name),
+		Value: value,
+	}
+}
+
+func Return(value ast.Expression) *ast.ReturnStatement {
+	return &ast.ReturnStatement{
+		Token:       token.Token{Type: token.RETURN, Literal: "return"},
+		ReturnValue: value,
+	}
+}
+
+// ExprStmt() wraps an expression in a statement. Like the parser, it uses the expression's first token as the
+// statement's token, e.g. `a` for `a + b`.
+
+func ExprStmt(expression ast.Expression) *ast.ExpressionStatement {
+	return &ast.ExpressionStatement{Token: firstToken(expression), Expression: expression}
+}
+
+func Block(statements ...ast.Statement) *ast.BlockStatement {
+	return &ast.BlockStatement{
+		Token:      token.Token{Type: token.LBRACE, Literal: "{"},
+		Statements: append([]ast.Statement{}, statements...),
+	}
+}
+
+func Ident(name string) *ast.Identifier {
+	return &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+}
+
+func Int(value int64) *ast.IntegerLiteral {
+	return &ast.IntegerLiteral{
+		Token: token.Token{Type: token.INT, Literal: strconv.FormatInt(value, 10)},
+		Value: value,
+	}
+}
+
+func Bool(value bool) *ast.Boolean {
+	if value {
+		return &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true}
+	}
+	return &ast.Boolean{Token: token.Token{Type: token.FALSE, Literal: "false"}, Value: false}
+}
+
+// Prefix() and Infix() take the operator as it's written in source, e.g. "!" or "==". The operator token types are
+// spelled the same as the operators themselves, so the token type is the operator.
+
+func Prefix(operator string, right ast.Expression) *ast.PrefixExpression {
+	return &ast.PrefixExpression{
+		Token:    token.Token{Type: token.TokenType(operator), Literal: operator},
+		Operator: operator,
+		Right:    right,
+	}
+}
+
+func Infix(left ast.Expression, operator string, right ast.Expression) *ast.InfixExpression {
+	return &ast.InfixExpression{
+		Token:    token.Token{Type: token.TokenType(operator), Literal: operator},
+		Left:     left,
+		Operator: operator,
+		Right:    right,
+	}
+}
+
+// If() builds an if expression; pass a nil alternative for an if without an else.
+
+func If(condition ast.Expression, consequence, alternative *ast.BlockStatement) *ast.IfExpression {
+	return &ast.IfExpression{
+		Token:       token.Token{Type: token.IF, Literal: "if"},
+		Condition:   condition,
+		Consequence: consequence,
+		Alternative: alternative,
+	}
+}
+
+func Fn(parameters []string, body *ast.BlockStatement) *ast.FunctionLiteral {
+	params := []*ast.Identifier{}
+	for _, p := range parameters {
+		params = append(params, Ident(p))
+	}
+
+	return &ast.FunctionLiteral{
+		Token:      token.Token{Type: token.FUNCTION, Literal: "fn"},
+		Parameters: params,
+		Body:       body,
+	}
+}
+
+func Call(function ast.Expression, arguments ...ast.Expression) *ast.CallExpression {
+	return &ast.CallExpression{
+		Token:     token.Token{Type: token.LPAREN, Literal: "("},
+		Function:  function,
+		Arguments: append([]ast.Expression{}, arguments...),
+	}
+}
+
+func firstToken(expression ast.Expression) token.Token {
+	switch expression := expression.(type) {
+	case *ast.InfixExpression:
+		return firstToken(expression.Left)
+	case *ast.CallExpression:
+		return firstToken(expression.Function)
+	case *ast.Identifier:
+		return expression.Token
+	case *ast.IntegerLiteral:
+		return expression.Token
+	case *ast.Boolean:
+		return expression.Token
+	case *ast.PrefixExpression:
+		return expression.Token
+	case *ast.IfExpression:
+		return expression.Token
+	case *ast.FunctionLiteral:
+		return expression.Token
+	case *ast.DecoratorExpression:
+		return expression.Token
+	}
+	return token.Token{}
+}
@@ -0,0 +1,107 @@
+package astbuilder
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"reflect"
+	"testing"
+)
+
+func TestBuilderMatchesHandWrittenNodes(t *testing.T) {
+	handWritten := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let"},
+				Name: &ast.Identifier{
+					Token: token.Token{Type: token.IDENT, Literal: "myVar"},
+					Value: "myVar",
+				},
+				Value: &ast.Identifier{
+					Token: token.Token{Type: token.IDENT, Literal: "anotherVar"},
+					Value: "anotherVar",
+				},
+			},
+		},
+	}
+
+	built := Program(Let("myVar", Ident("anotherVar")))
+
+	if built.String() != handWritten.String() {
+		t.Errorf("String() differs. built=%q, hand-written=%q", built.String(), handWritten.String())
+	}
+	if !reflect.DeepEqual(built, handWritten) {
+		t.Errorf("built node differs from the hand-written one.\nbuilt=%+v\nhand-written=%+v", built, handWritten)
+	}
+}
+
+func TestBuilderMatchesParser(t *testing.T) {
+	tests := []struct {
+		input string
+		built *ast.Program
+	}{
+		{
+			"let x = 5 + -y * 2;",
+			Program(Let("x", Infix(Int(5), "+", Infix(Prefix("-", Ident("y")), "*", Int(2))))),
+		},
+		{
+			"a == b;",
+			Program(ExprStmt(Infix(Ident("a"), "==", Ident("b")))),
+		},
+		{
+			"let add = fn(x, y) { return x + y; }; add(1, !true);",
+			Program(
+				Let("add", Fn([]string{"x", "y"}, Block(Return(Infix(Ident("x"), "+", Ident("y")))))),
+				ExprStmt(Call(Ident("add"), Int(1), Prefix("!", Bool(true)))),
+			),
+		},
+		{
+			"if (x < y) { x } else { false }",
+			Program(ExprStmt(If(Infix(Ident("x"), "<", Ident("y")),
+				Block(ExprStmt(Ident("x"))),
+				Block(ExprStmt(Bool(false)))))),
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := parser.New(l)
+		parsed := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		if tt.built.String() != parsed.String() {
+			t.Errorf("String() differs for %q. built=%q, parsed=%q", tt.input, tt.built.String(), parsed.String())
+		}
+		// the builders don't know where anything is in the source, so positions can't be compared
+		clearPositions(reflect.ValueOf(parsed))
+		if !reflect.DeepEqual(tt.built, parsed) {
+			t.Errorf("built tree differs from the parsed one for %q", tt.input)
+		}
+	}
+}
+
+// clearPositions() zeroes the Line and Column of every token in the tree rooted at v.
+
+func clearPositions(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			clearPositions(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			clearPositions(v.Index(i))
+		}
+	case reflect.Struct:
+		if tok, ok := v.Addr().Interface().(*token.Token); ok {
+			tok.Line, tok.Column = 0, 0
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			clearPositions(v.Field(i))
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"monkey/object"
+	"sort"
+	"strings"
+)
+
+// DefaultOutputLimit is the number of bytes a program may write with puts by default. It's only there to stop a
+// runaway program from flooding the terminal, so it's far more than any sensible program prints.
+const DefaultOutputLimit = 64 << 20
+
+// ErrOutputLimit is the error a writer returned by LimitOutput() fails with once its limit is reached.
+var ErrOutputLimit = errors.New("output limit exceeded")
+
+// LimitOutput() returns a writer that passes at most limit bytes on to w. The write that crosses the limit is cut off
+// at it, and it and every write after it fail with ErrOutputLimit; puts turns that into a Monkey error.
+
+func LimitOutput(w io.Writer, limit int64) io.Writer {
+	return &limitedWriter{w: w, remaining: limit}
+}
+
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) <= lw.remaining {
+		n, err := lw.w.Write(p)
+		lw.remaining -= int64(n)
+		return n, err
+	}
+
+	n, err := lw.w.Write(p[:lw.remaining])
+	lw.remaining -= int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, ErrOutputLimit
+}
+
+// builtins holds the functions that are available in every Monkey program without having to be defined. They're
+// looked up after the environment, so a program can shadow them with its own bindings.
+
+var builtins = map[string]*object.Builtin{
+	"puts": {Fn: func(ctx object.BuiltinContext, args ...object.Object) object.Object {
+		for _, arg := range args {
+			if _, err := io.WriteString(ctx.Output(), arg.Inspect()+"\n"); errors.Is(err, ErrOutputLimit) {
+				return newError(object.OutputLimit, "output limit exceeded")
+			}
+		}
+		return NULL
+	}},
+	"memoize": {Fn: memoize},
+	"min": {Fn: func(_ object.BuiltinContext, args ...object.Object) object.Object {
+		return pickInteger("min", args, func(candidate, current int64) bool { return candidate < current })
+	}},
+	"max": {Fn: func(_ object.BuiltinContext, args ...object.Object) object.Object {
+		return pickInteger("max", args, func(candidate, current int64) bool { return candidate > current })
+	}},
+	"abs": {Fn: func(_ object.BuiltinContext, args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return newError(object.Arity, "wrong number of arguments. got=%d, want=1", len(args))
+		}
+
+		integer, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError(object.TypeMismatch, "argument to `abs` must be INTEGER, got %s", args[0].Type())
+		}
+
+		if integer.Value < 0 {
+			return &object.Integer{Value: -integer.Value}
+		}
+		return integer
+	}},
+	"gcd": {Fn: func(_ object.BuiltinContext, args ...object.Object) object.Object {
+		a, b, errObj := integerPair("gcd", args)
+		if errObj != nil {
+			return errObj
+		}
+
+		if a < 0 {
+			a = -a
+		}
+		if b < 0 {
+			b = -b
+		}
+		for b != 0 {
+			a, b = b, a%b
+		}
+		return &object.Integer{Value: a}
+	}},
+	// pow() is integer exponentiation with the same wrap-around on overflow as the other integer operators. Negative
+	// exponents are an error since their results aren't integers and Monkey has no float type.
+	"pow": {Fn: func(_ object.BuiltinContext, args ...object.Object) object.Object {
+		base, exp, errObj := integerPair("pow", args)
+		if errObj != nil {
+			return errObj
+		}
+		if exp < 0 {
+			return newError(object.TypeMismatch, "negative exponent for `pow`: %d", exp)
+		}
+
+		result := int64(1)
+		for exp > 0 {
+			if exp&1 == 1 {
+				result *= base
+			}
+			base *= base
+			exp >>= 1
+		}
+		return &object.Integer{Value: result}
+	}},
+}
+
+// BuiltinNames() returns the names of all builtin functions in sorted order.
+
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// memoize() wraps a function in a builtin that remembers its results. Calls are cached by the hash keys of their
+// arguments; a call with an argument that isn't hashable (a function, say) just goes through to the wrapped function
+// every time. Caching only makes sense for pure functions, which is up to the caller.
+
+func memoize(_ object.BuiltinContext, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError(object.Arity, "wrong number of arguments. got=%d, want=1", len(args))
+	}
+
+	fn := args[0]
+	switch fn.(type) {
+	case *object.Function, *object.Builtin:
+	default:
+		return newError(object.TypeMismatch, "argument to `memoize` must be FUNCTION, got %s", fn.Type())
+	}
+
+	cache := make(map[string]object.Object)
+
+	// the wrapped function runs with the settings of whichever evaluation calls the memoized one
+	return &object.Builtin{Fn: func(ctx object.BuiltinContext, args ...object.Object) object.Object {
+		key, ok := cacheKey(args)
+		if !ok {
+			return ctx.Apply(fn, args)
+		}
+
+		if result, ok := cache[key]; ok {
+			return result
+		}
+		// errors aren't cached: one may come from the calling evaluation's settings, like its output limit, rather
+		// than from the arguments
+		result := ctx.Apply(fn, args)
+		if !isError(result) {
+			cache[key] = result
+		}
+		return result
+	}}
+}
+
+// cacheKey() combines the hash keys of args into a single map key. It reports false if any argument isn't hashable.
+
+func cacheKey(args []object.Object) (string, bool) {
+	var key strings.Builder
+	for _, arg := range args {
+		hashable, ok := arg.(object.Hashable)
+		if !ok {
+			return "", false
+		}
+		hashKey := hashable.HashKey()
+		fmt.Fprintf(&key, "%s:%d,", hashKey.Type, hashKey.Value)
+	}
+	return key.String(), true
+}
+
+func integerPair(name string, args []object.Object) (int64, int64, *object.Error) {
+	if len(args) != 2 {
+		return 0, 0, newError(object.Arity, "wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	a, ok := args[0].(*object.Integer)
+	if !ok {
+		return 0, 0, newError(object.TypeMismatch, "argument to `%s` must be INTEGER, got %s", name, args[0].Type())
+	}
+	b, ok := args[1].(*object.Integer)
+	if !ok {
+		return 0, 0, newError(object.TypeMismatch, "argument to `%s` must be INTEGER, got %s", name, args[1].Type())
+	}
+
+	return a.Value, b.Value, nil
+}
+
+// pickInteger() implements min and max: it requires at least one argument, all of them integers, and returns the one
+// for which better(candidate, current) held against every other argument.
+
+func pickInteger(name string, args []object.Object, better func(candidate, current int64) bool) object.Object {
+	if len(args) == 0 {
+		return newError(object.Arity, "wrong number of arguments. got=0, want at least 1")
+	}
+
+	var result *object.Integer
+	for _, arg := range args {
+		integer, ok := arg.(*object.Integer)
+		if !ok {
+			return newError(object.TypeMismatch, "argument to `%s` must be INTEGER, got %s", name, arg.Type())
+		}
+		if result == nil || better(integer.Value, result.Value) {
+			result = integer
+		}
+	}
+
+	return result
+}
@@ -1,44 +1,158 @@
 package evaluator
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"monkey/ast"
 	"monkey/object"
+	"os"
 )
 
 // They are used so that we don't have to create a new object.Boolean every time we need a true or false value.
-// The same goes for NULL.
+// The same goes for NULL. They're the object package's singletons, so every package shares the same instances and
+// comparing pointers is enough to compare these values.
 var (
 	// NULL is a singleton object
-	NULL = &object.Null{}
+	NULL = object.NULL
 
 	// TRUE is a singleton object
-	TRUE = &object.Boolean{Value: true}
+	TRUE = object.TRUE
 
 	// FALSE is a singleton object
-	FALSE = &object.Boolean{Value: false}
+	FALSE = object.FALSE
 )
 
+// TruthinessMode selects the predicate used by `if` and `!` to decide whether a value counts as true.
+
+type TruthinessMode int
+
+const (
+	// StrictTruthiness is Monkey's default: only false and null are falsy, everything else (including 0) is truthy.
+	StrictTruthiness TruthinessMode = iota
+	// ExtendedTruthiness additionally treats the integer 0 as falsy. Once strings and arrays exist, "" and [] belong
+	// here too.
+	ExtendedTruthiness
+)
+
+// Config holds the settings programs are evaluated with. The zero value is standard Monkey. A Config is passed to
+// every evaluation rather than kept in package variables, so evaluations with different settings can run side by side.
+
+type Config struct {
+	// Truthiness is the mode `if` and `!` use. The zero value is StrictTruthiness.
+	Truthiness TruthinessMode
+
+	// Output is where puts writes to. nil means os.Stdout.
+	Output io.Writer
+
+	// OutputLimit is how many bytes a single evaluation may write to Output before puts fails. Every Eval or Apply
+	// starts with the full limit, so one runaway program doesn't use it up for the ones after it. Zero means
+	// DefaultOutputLimit.
+	OutputLimit int64
+}
+
+// Eval() evaluates node in env with the settings in c.
+
+func (c Config) Eval(node ast.Node, env *object.Environment) object.Object {
+	return c.newEvaluation().eval(node, env)
+}
+
+// Apply() calls fn, a Monkey function or builtin, with args and returns the result, with the settings in c. It's how
+// host code calls back into Monkey functions it got hold of.
+
+func (c Config) Apply(fn object.Object, args []object.Object) object.Object {
+	return c.newEvaluation().applyFunction(fn, args)
+}
+
+// Eval() evaluates node in env with the default Config.
+
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return Config{}.Eval(node, env)
+}
+
+// Apply() is Config.Apply() with the default Config.
+
+func Apply(fn object.Object, args []object.Object) object.Object {
+	return Config{}.Apply(fn, args)
+}
+
+// evaluation is a single run of Config.Eval() or Config.Apply(). The evaluator's functions are its methods, so the
+// settings reach every one of them without being passed around separately. It's also the object.BuiltinContext
+// builtins get to see.
+
+type evaluation struct {
+	truthiness TruthinessMode
+	output     io.Writer
+}
+
+func (c Config) newEvaluation() *evaluation {
+	output, limit := c.Output, c.OutputLimit
+	if output == nil {
+		output = os.Stdout
+	}
+	if limit == 0 {
+		limit = DefaultOutputLimit
+	}
+	return &evaluation{truthiness: c.Truthiness, output: LimitOutput(output, limit)}
+}
+
+// Output() is where builtins like puts write to.
+
+func (e *evaluation) Output() io.Writer {
+	return e.output
+}
+
+// Apply() lets builtins like memoize call functions with the same settings as the evaluation that called them.
+
+func (e *evaluation) Apply(fn object.Object, args []object.Object) object.Object {
+	return e.applyFunction(fn, args)
+}
+
+func (e *evaluation) eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	// Statements
 	case *ast.Program:
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return e.eval(node.Expression, env)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		if node.ReturnValue == nil { // a bare `return;`
+			return &object.ReturnValue{Value: NULL}
+		}
+		val := e.eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := e.eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
 		env.Set(node.Name.Value, val)
+	case *ast.MultiLetStatement:
+		vals := e.evalMultipleValues(len(node.Names), node.Values, env)
+		if len(vals) == 1 && isError(vals[0]) {
+			return vals[0]
+		}
+		for i, name := range node.Names {
+			env.Set(name.Value, vals[i])
+		}
+	case *ast.MultiAssignStatement:
+		vals := e.evalMultipleValues(len(node.Targets), node.Values, env)
+		if len(vals) == 1 && isError(vals[0]) {
+			return vals[0]
+		}
+		// every target is checked first, so an unknown one doesn't leave the others assigned
+		for _, target := range node.Targets {
+			if _, ok := env.Get(target.Value); !ok {
+				return newError(object.UnknownIdentifier, "identifier not found: "+target.Value)
+			}
+		}
+		for i, target := range node.Targets {
+			env.Assign(target.Value, vals[i])
+		}
 
 	// Expressions
 	case *ast.IntegerLiteral:
@@ -46,29 +160,29 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := e.eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return e.evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		left := e.eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right, env)
+		right := e.eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 		return evalInfixExpression(node.Operator, left, right)
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return e.evalBlockStatement(node, env)
 	case *ast.IfExpression:
-		condition := Eval(node.Condition, env)
+		condition := e.eval(node.Condition, env)
 		if isError(condition) {
 			return condition
 		}
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.FunctionLiteral:
@@ -79,26 +193,44 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			Body:       body,
 			Env:        env,
 		}
+	case *ast.DecoratorExpression:
+		function := e.eval(node.Function, env)
+		if isError(function) {
+			return function
+		}
+		decorator := e.eval(node.Decorator, env)
+		if isError(decorator) {
+			return decorator
+		}
+		return e.applyFunction(decorator, []object.Object{function})
+	case *ast.LetInExpression:
+		val := e.eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		inner := object.NewEnclosedEnvironment(env)
+		inner.Set(node.Name.Value, val)
+		return e.eval(node.Body, inner)
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		function := e.eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
+		args := e.evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		return e.applyFunction(function, args)
 	}
 
 	return nil
 }
 
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (e *evaluation) evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range program.Statements {
-		result = Eval(statement, env)
+		result = e.eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -111,6 +243,19 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	return result
 }
 
+// evalMultipleValues() evaluates the right-hand side of a multiple let/assignment. Every value is evaluated before
+// anything is bound, so `a, b = b, a` swaps.
+
+func (e *evaluation) evalMultipleValues(targets int, values []ast.Expression, env *object.Environment) []object.Object {
+	if targets != len(values) {
+		return []object.Object{
+			newError(object.Arity, "assignment mismatch: %d targets, %d values", targets, len(values)),
+		}
+	}
+
+	return e.evalExpressions(values, env)
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return TRUE
@@ -118,33 +263,24 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	return FALSE
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+func (e *evaluation) evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
 	case "!":
-		return evalBangOperatorExpression(right)
+		return e.evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError(object.UnknownOperator, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
-func evalBangOperatorExpression(right object.Object) object.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NULL:
-		return TRUE
-	default:
-		return FALSE
-	}
+func (e *evaluation) evalBangOperatorExpression(right object.Object) object.Object {
+	return nativeBoolToBooleanObject(!e.isTruthy(right))
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+		return newError(object.UnknownOperator, "unknown operator: -%s", right.Type())
 	}
 
 	value := right.(*object.Integer).Value
@@ -160,9 +296,9 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	case operator == "!=":
 		return nativeBoolToBooleanObject(left != right) // compare pointers
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.TypeMismatch, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.UnknownOperator, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -171,14 +307,8 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	rightVal := right.(*object.Integer).Value
 
 	switch operator {
-	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
-	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
-	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
-	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+	case "+", "-", "*", "/":
+		return evalIntegerArithmetic(operator, left.(*object.Integer), right)
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -188,26 +318,53 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.UnknownOperator, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
-	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+// evalIntegerArithmetic() delegates to the arithmetic methods on object.Integer, which own the overflow and division
+// rules, and turns their Go errors into Monkey errors.
+
+func evalIntegerArithmetic(operator string, left *object.Integer, right object.Object) object.Object {
+	var result object.Object
+	var err error
+
+	switch operator {
+	case "+":
+		result, err = left.Add(right)
+	case "-":
+		result, err = left.Sub(right)
+	case "*":
+		result, err = left.Mul(right)
+	case "/":
+		result, err = left.Div(right)
+	}
+
+	switch {
+	case errors.Is(err, object.ErrDivisionByZero):
+		return newError(object.DivByZero, "%s", err)
+	case err != nil:
+		return newError(object.TypeMismatch, "%s", err)
+	}
+	return result
+}
+
+func (e *evaluation) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.eval(ie.Condition, env)
+	if e.isTruthy(condition) {
+		return e.eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return e.eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+func (e *evaluation) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		result = e.eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
@@ -220,7 +377,7 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
-func isTruthy(obj object.Object) bool {
+func (e *evaluation) isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
 		return false
@@ -228,13 +385,19 @@ func isTruthy(obj object.Object) bool {
 		return true
 	case FALSE:
 		return false
-	default:
-		return true
 	}
+
+	if e.truthiness == ExtendedTruthiness {
+		if integer, ok := obj.(*object.Integer); ok {
+			return integer.Value != 0
+		}
+	}
+
+	return true
 }
 
-func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+func newError(kind object.ErrorKind, format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
 }
 
 func isError(obj object.Object) bool {
@@ -245,21 +408,25 @@ func isError(obj object.Object) bool {
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	val, ok := env.Get(node.Value)
-	if !ok {
-		return newError("identifier not found: " + node.Value)
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+
+	if builtin, ok := builtins[node.Value]; ok {
+		return builtin
 	}
-	return val
+
+	return newError(object.UnknownIdentifier, "identifier not found: "+node.Value)
 }
 
-func evalExpressions(
+func (e *evaluation) evalExpressions(
 	exps []ast.Expression,
 	env *object.Environment,
 ) []object.Object {
 	var result []object.Object
 
-	for _, e := range exps {
-		evaluated := Eval(e, env) // evaluate them in the context of the current environment
+	for _, exp := range exps {
+		evaluated := e.eval(exp, env) // evaluate them in the context of the current environment
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -269,15 +436,108 @@ func evalExpressions(
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	function, ok := fn.(*object.Function)
+func (e *evaluation) applyFunction(fn object.Object, args []object.Object) object.Object {
+	switch fn := fn.(type) {
+	case *object.Function:
+		// A self tail call (`return f(...)` inside f) doesn't recurse: evalFunctionBody() hands back the new
+		// arguments and we run the body again in a fresh environment, so tail-recursive functions use constant stack.
+		for {
+			if len(args) != len(fn.Parameters) {
+				return newError(object.Arity, "wrong number of arguments. got=%d, want=%d",
+					len(args), len(fn.Parameters))
+			}
+
+			extendenEnv := extendFunctionEnv(fn, args)
+			evaluated, call := e.evalFunctionBody(fn, fn.Body, extendenEnv)
+			if call == nil {
+				return unwrapReturnValue(evaluated)
+			}
+			args = call.args
+		}
+	case *object.Builtin:
+		return fn.Fn(e, args...)
+	default:
+		return newError(object.NotCallable, "not a function: %s", fn.Type())
+	}
+}
+
+// tailCall is what evalFunctionBody() returns instead of a result when the body ended in a self tail call.
+
+type tailCall struct {
+	args []object.Object
+}
+
+// evalFunctionBody() evaluates a block of fn's body just like evalBlockStatement() does, except that it recognizes
+// `return fn(...)`, both directly in the block and inside the branches of if expressions. In that case it evaluates
+// the arguments and returns them as a tailCall instead of calling fn.
+
+func (e *evaluation) evalFunctionBody(fn *object.Function, block *ast.BlockStatement, env *object.Environment) (object.Object, *tailCall) {
+	var result object.Object
+
+	for _, statement := range block.Statements {
+		switch statement := statement.(type) {
+		case *ast.ReturnStatement:
+			if call, ok := statement.ReturnValue.(*ast.CallExpression); ok && isSelfCall(fn, call, env) {
+				args := e.evalExpressions(call.Arguments, env)
+				if len(args) == 1 && isError(args[0]) {
+					return args[0], nil
+				}
+				return nil, &tailCall{args: args}
+			}
+			result = e.eval(statement, env)
+		case *ast.ExpressionStatement:
+			ifExpression, ok := statement.Expression.(*ast.IfExpression)
+			if !ok {
+				result = e.eval(statement, env)
+				break
+			}
+
+			var call *tailCall
+			result, call = e.evalIfInFunctionBody(fn, ifExpression, env)
+			if call != nil {
+				return nil, call
+			}
+		default:
+			result = e.eval(statement, env)
+		}
+
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return result, nil
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (e *evaluation) evalIfInFunctionBody(fn *object.Function, ie *ast.IfExpression, env *object.Environment) (object.Object, *tailCall) {
+	condition := e.eval(ie.Condition, env)
+	if isError(condition) {
+		return condition, nil
+	}
+
+	if e.isTruthy(condition) {
+		return e.evalFunctionBody(fn, ie.Consequence, env)
+	} else if ie.Alternative != nil {
+		return e.evalFunctionBody(fn, ie.Alternative, env)
+	} else {
+		return NULL, nil
+	}
+}
+
+// isSelfCall() reports whether call calls fn through a plain identifier. Only identifiers are checked because
+// looking them up has no side effects, so the callee isn't evaluated twice when it turns out not to be fn.
+
+func isSelfCall(fn *object.Function, call *ast.CallExpression, env *object.Environment) bool {
+	ident, ok := call.Function.(*ast.Identifier)
 	if !ok {
-		return newError("not a function: %s", fn.Type())
+		return false
 	}
 
-	extendenEnv := extendFunctionEnv(function, args)
-	evaluated := Eval(function.Body, extendenEnv)
-	return unwrapReturnValue(evaluated)
+	callee, ok := env.Get(ident.Value)
+	return ok && callee == fn
 }
 
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
@@ -1,9 +1,13 @@
 package evaluator
 
 import (
+	"bytes"
+	"monkey/astbuilder"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"sort"
+	"sync"
 	"testing"
 )
 
@@ -98,6 +102,9 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else if (1 < 2) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (1 > 2) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (1 > 2) { 20 }", nil},
 	}
 
 	for _, tt := range tests {
@@ -112,6 +119,63 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 }
 
+func TestTruthinessModes(t *testing.T) {
+	tests := []struct {
+		input    string
+		strict   interface{}
+		extended interface{}
+	}{
+		{"if (0) { 10 } else { 20 }", 10, 20},
+		{"if (1) { 10 } else { 20 }", 10, 10},
+		{"if (if (false) { 1 }) { 10 } else { 20 }", 20, 20},
+		{"if (false) { 10 } else { 20 }", 20, 20},
+		{"!0", false, true},
+		{"!1", false, false},
+		{"!!0", true, false},
+	}
+
+	for _, mode := range []TruthinessMode{StrictTruthiness, ExtendedTruthiness} {
+		for _, tt := range tests {
+			expected := tt.strict
+			if mode == ExtendedTruthiness {
+				expected = tt.extended
+			}
+
+			evaluated := testEvalWith(tt.input, Config{Truthiness: mode})
+			switch expected := expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case bool:
+				testBooleanObject(t, evaluated, expected)
+			}
+		}
+	}
+}
+
+func TestConfigIsPerEvaluation(t *testing.T) {
+	input := "let f = fn(x) { if (x) { 1 } else { 2 } }; memoize(f)(0) + f(0)"
+
+	// evaluations with different settings don't see each other's, even when they run at the same time
+	var wg sync.WaitGroup
+	results := make([]object.Object, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = testEvalWith(input, Config{Truthiness: TruthinessMode(i % 2)})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		expected := int64(2) // 1 + 1 with strict truthiness
+		if TruthinessMode(i%2) == ExtendedTruthiness {
+			expected = 4
+		}
+		testIntegerObject(t, result, expected)
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -138,6 +202,43 @@ return 1; }
 	}
 }
 
+func TestNestedFunctionReturns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		// the inner return only ends the inner call; the outer body carries on
+		{"let outer = fn() { let inner = fn() { return 1; 2 }; inner(); 3 }; outer();", 3},
+		{"let outer = fn() { let x = fn() { return 1; }(); x + 10 }; outer();", 11},
+		{"let outer = fn() { if (true) { fn() { return 1; }(); } 4 }; outer();", 4},
+		{"let outer = fn(f) { f(); 5 }; outer(fn() { return 1; });", 5},
+		{"let outer = fn() { let inner = fn() { return; }; inner(); 6 }; outer();", 6},
+		// and the outer function still returns normally afterwards
+		{"let outer = fn() { let inner = fn() { return 1; }; return inner() + 1; 100 }; outer();", 2},
+		{"let outer = fn(n) { let inner = fn() { return n; }; if (inner() > 0) { return 7; } 8 }; outer(1);", 7},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestBareReturn(t *testing.T) {
+	tests := []string{
+		"return;",
+		"return; 10;",
+		"let f = fn() { return; 10 }; f();",
+		"let f = fn() { return }; f();",
+		"let f = fn(x) { if (x > 1) { return; } x }; f(2);",
+	}
+
+	for _, input := range tests {
+		testNullObject(t, testEval(input))
+	}
+
+	testIntegerObject(t, testEval("let f = fn(x) { if (x > 1) { return; } x }; f(1);"), 1)
+}
+
 func TestErrorHandling(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -181,6 +282,10 @@ return 1; }
 			"foobar",
 			"identifier not found: foobar",
 		},
+		{
+			"5 / 0",
+			"division by zero",
+		},
 	}
 
 	for _, tt := range tests {
@@ -196,6 +301,63 @@ return 1; }
 	}
 }
 
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected object.ErrorKind
+	}{
+		{"5 + true;", object.TypeMismatch},
+		{"foobar", object.UnknownIdentifier},
+		{"5 / 0", object.DivByZero},
+		{"-true", object.UnknownOperator},
+		{"true + false", object.UnknownOperator},
+		{"5(1)", object.NotCallable},
+		{"abs(1, 2)", object.Arity},
+		{"fn(x) { x }(1, 2)", object.Arity},
+		{"fn(x, y) { x }(1)", object.Arity},
+		{"abs(true)", object.TypeMismatch},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.expected {
+			t.Errorf("wrong error kind for %q. expected=%s, got=%s (%s)", tt.input, tt.expected, errObj.Kind,
+				errObj.Message)
+		}
+	}
+}
+
+func TestCallingNonFunction(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5(3)", "not a function: INTEGER"},
+		{"let x = 5; x(1, 2)", "not a function: INTEGER"},
+		{"true()", "not a function: BOOLEAN"},
+		{"if (false) { 1 }(1)", "not a function: NULL"},
+		{"fn() { 5 }()(1)", "not a function: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != object.NotCallable || errObj.Message != tt.expected {
+			t.Errorf("wrong error for %q. expected=%q (%s), got=%q (%s)", tt.input, tt.expected, object.NotCallable,
+				errObj.Message, errObj.Kind)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -212,6 +374,70 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestLetInExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 5 in x + 1", 6},
+		{"let x = 5 in let y = x * 2 in x + y", 15},
+		{"let x = 1 in let x = x + 1 in x", 2},
+		{"10 + let x = 5 in x * 2", 20},
+		{"let f = fn(n) { let m = n in m * m }; f(4)", 16},
+		// the name is local to the body
+		{"let x = 1; let y = let x = 2 in x; x + y", 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval("let y = let x = 2 in x; x")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "identifier not found: x" {
+		t.Errorf("let-in name leaked out of its body. got=%T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestMultipleAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a, b = 1, 2; a;", 1},
+		{"let a, b = 1, 2; b;", 2},
+		{"let a, b, c = 1, 2 * 3, a; c;", "identifier not found: a"},
+		{"let a, b = 1, 2; a, b = b, a; a * 10 + b;", 21},
+		{"let a, b = 1, 2; let f = fn() { a, b = b, a + b; }; f(); f(); a * 10 + b;", 35},
+		{"let a, b = 1, 2, 3;", "assignment mismatch: 2 targets, 3 values"},
+		{"let a, b = 1, 2; a, b = 3;", "assignment mismatch: 2 targets, 1 values"},
+		{"let a = 1; a, b = 2, 3;", "identifier not found: b"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+
+	// a failed assignment leaves every target as it was
+	env := object.NewEnvironment()
+	Eval(parser.New(lexer.New("let a = 1; a, b = 2, 3;")).ParseProgram(), env)
+	testIntegerObject(t, Eval(parser.New(lexer.New("a")).ParseProgram(), env), 1)
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2 };"
 
@@ -264,13 +490,126 @@ addTwo(2);`
 
 	testIntegerObject(t, testEval(input), 4)
 }
+
+func TestTailCallOptimization(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			`let sum = fn(n, acc) {
+				if (n == 0) { return acc; }
+				return sum(n - 1, acc + n);
+			};
+			sum(1000000, 0);`,
+			500000500000,
+		},
+		{
+			`let count = fn(n, acc) {
+				if (n == 0) { acc } else { return count(n - 1, acc + 1); }
+			};
+			count(1000000, 0);`,
+			1000000,
+		},
+		{
+			// not a tail call: the result of the recursive call is still used, so it recurses normally
+			`let fact = fn(n) { if (n == 0) { return 1; } return n * fact(n - 1); };
+			fact(10);`,
+			3628800,
+		},
+		{
+			// a tail call to a different function is evaluated normally
+			`let double = fn(x) { x * 2 };
+			let f = fn(x) { return double(x); };
+			f(21);`,
+			42,
+		},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestDecorators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{
+			`let twice = fn(f) { fn(x) { f(f(x)) } };
+			let addTwo = @twice fn(x) { x + 1 };
+			addTwo(5);`,
+			7,
+		},
+		{
+			`let scale = fn(n) { fn(f) { fn(x) { f(x) * n } } };
+			let g = @scale(3) fn(x) { x + 1 };
+			g(1);`,
+			6,
+		},
+		{
+			// stacked decorators apply from the inside out: double(inc(x))
+			`let double = fn(f) { fn(x) { f(x) * 2 } };
+			let inc = fn(f) { fn(x) { f(x) + 1 } };
+			let h = @double @inc fn(x) { x };
+			h(10);`,
+			22,
+		},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestEvalBuiltProgram(t *testing.T) {
+	// let double = fn(x) { x * 2 }; double(21);
+	program := astbuilder.Program(
+		astbuilder.Let("double", astbuilder.Fn([]string{"x"},
+			astbuilder.Block(astbuilder.ExprStmt(astbuilder.Infix(astbuilder.Ident("x"), "*", astbuilder.Int(2)))))),
+		astbuilder.ExprStmt(astbuilder.Call(astbuilder.Ident("double"), astbuilder.Int(21))),
+	)
+
+	testIntegerObject(t, Eval(program, object.NewEnvironment()), 42)
+}
+
+func TestSingletonIdentity(t *testing.T) {
+	tests := []struct {
+		inputs   []string
+		expected object.Object
+	}{
+		{[]string{"if (false) { 1 }", "puts()", "if (1 > 2) { 1 } else { if (false) { 2 } }"}, object.NULL},
+		{[]string{"true", "1 < 2", "!false", "5 == 5", "true == true", "!!1"}, object.TRUE},
+		{[]string{"false", "1 > 2", "!true", "5 != 5", "true != true", "!1"}, object.FALSE},
+	}
+
+	for _, tt := range tests {
+		for _, input := range tt.inputs {
+			evaluated := testEval(input)
+			if evaluated != tt.expected {
+				t.Errorf("%q didn't evaluate to the %s singleton. got=%T (%p), want %p",
+					input, tt.expected.Inspect(), evaluated, evaluated, tt.expected)
+			}
+		}
+	}
+
+	if NULL != object.NULL || TRUE != object.TRUE || FALSE != object.FALSE {
+		t.Errorf("evaluator singletons are not the object package's singletons")
+	}
+}
+
 func testEval(input string) object.Object {
+	return testEvalWith(input, Config{})
+}
+
+func testEvalWith(input string, config Config) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
 	env := object.NewEnvironment()
 
-	return Eval(program, env)
+	return config.Eval(program, env)
 }
 
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
@@ -311,3 +650,168 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 
 	return true
 }
+
+func TestPuts(t *testing.T) {
+	var buf bytes.Buffer
+	evaluated := testEvalWith("puts(1, 2 * 3); puts(true)", Config{Output: &buf})
+	testNullObject(t, evaluated)
+
+	if buf.String() != "1\n6\ntrue\n" {
+		t.Errorf("wrong output. got=%q", buf.String())
+	}
+}
+
+func TestOutputLimit(t *testing.T) {
+	var buf bytes.Buffer
+	config := Config{Output: &buf, OutputLimit: 10}
+
+	evaluated := testEvalWith("let spam = fn(n) { puts(n); spam(n + 1) }; spam(0)", config)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Kind != object.OutputLimit || errObj.Message != "output limit exceeded" {
+		t.Errorf("wrong error. got=%s (%s)", errObj.Message, errObj.Kind)
+	}
+	if buf.String() != "0\n1\n2\n3\n4\n" {
+		t.Errorf("wrong output. got=%q", buf.String())
+	}
+
+	// the next evaluation with the same config starts with the full limit again
+	buf.Reset()
+	evaluated = testEvalWith("puts(1)", config)
+	if evaluated != NULL {
+		t.Errorf("puts failed in a new evaluation. got=%T(%+v)", evaluated, evaluated)
+	}
+	if buf.String() != "1\n" {
+		t.Errorf("wrong output in a new evaluation. got=%q", buf.String())
+	}
+}
+
+func TestBuiltinNames(t *testing.T) {
+	names := BuiltinNames()
+
+	if len(names) != len(builtins) {
+		t.Fatalf("wrong number of names. want=%d, got=%d (%v)", len(builtins), len(names), names)
+	}
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("names are not sorted. got=%v", names)
+	}
+	for _, name := range []string{"puts", "min", "max", "memoize"} {
+		if i := sort.SearchStrings(names, name); i == len(names) || names[i] != name {
+			t.Errorf("%q is missing from %v", name, names)
+		}
+	}
+}
+
+func TestMemoize(t *testing.T) {
+	var buf bytes.Buffer
+	config := Config{Output: &buf}
+
+	input := `
+let double = memoize(fn(x) { puts(x); x * 2 });
+double(2) + double(2) + double(3) + double(2) + double(3);
+`
+	testIntegerObject(t, testEvalWith(input, config), 4+4+6+4+6)
+	if buf.String() != "2\n3\n" {
+		t.Errorf("memoized function ran the wrong number of times. output=%q", buf.String())
+	}
+
+	// functions aren't hashable, so calls with one bypass the cache
+	buf.Reset()
+	input = `
+let call = memoize(fn(f, x) { puts(x); f(x) });
+let inc = fn(x) { x + 1 };
+call(inc, 1) + call(inc, 1);
+`
+	testIntegerObject(t, testEvalWith(input, config), 4)
+	if buf.String() != "1\n1\n" {
+		t.Errorf("call with unhashable argument was cached. output=%q", buf.String())
+	}
+
+	buf.Reset()
+	input = `
+let fib = memoize(fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } });
+fib(80);
+`
+	testIntegerObject(t, testEval(input), 23416728348467685)
+
+	// a call that fails isn't cached, so it runs again when called from an evaluation with a higher output limit
+	buf.Reset()
+	memoized := testEval("memoize(fn(x) { puts(123456); x })")
+	args := []object.Object{&object.Integer{Value: 1}}
+	low, high := Config{Output: &buf, OutputLimit: 5}, Config{Output: &buf, OutputLimit: 1000}
+	if errObj, ok := low.Apply(memoized, args).(*object.Error); !ok || errObj.Kind != object.OutputLimit {
+		t.Errorf("expected an output limit error. got=%+v", errObj)
+	}
+	testIntegerObject(t, high.Apply(memoized, args), 1)
+	if buf.String() != "12345123456\n" {
+		t.Errorf("failed call was cached. output=%q", buf.String())
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"memoize(1)", "argument to `memoize` must be FUNCTION, got INTEGER"},
+		{"memoize()", "wrong number of arguments. got=0, want=1"},
+		{"memoize(fn(x) { x })(1, 2)", "wrong number of arguments. got=2, want=1"},
+	}
+	for _, tt := range errors {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok || errObj.Message != tt.expected {
+			t.Errorf("wrong result for %q. want error %q, got=%+v", tt.input, tt.expected, errObj)
+		}
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"min(3, 1, 2)", 1},
+		{"min(-5)", -5},
+		{"max(3, 1, 2)", 3},
+		{"max(1, 2 * 5, 7)", 10},
+		{"max(4)", 4},
+		{"abs(-7)", 7},
+		{"abs(7)", 7},
+		{"abs(0)", 0},
+		{"min()", "wrong number of arguments. got=0, want at least 1"},
+		{"max()", "wrong number of arguments. got=0, want at least 1"},
+		{"abs()", "wrong number of arguments. got=0, want=1"},
+		{"abs(1, 2)", "wrong number of arguments. got=2, want=1"},
+		{"min(1, true)", "argument to `min` must be INTEGER, got BOOLEAN"},
+		{"abs(false)", "argument to `abs` must be INTEGER, got BOOLEAN"},
+		{"gcd(12, 18)", 6},
+		{"gcd(-12, 18)", 6},
+		{"gcd(7, 0)", 7},
+		{"gcd(12)", "wrong number of arguments. got=1, want=2"},
+		{"gcd(12, true)", "argument to `gcd` must be INTEGER, got BOOLEAN"},
+		{"pow(2, 10)", 1024},
+		{"pow(-3, 3)", -27},
+		{"pow(5, 0)", 1},
+		{"pow(2, -1)", "negative exponent for `pow`: -1"},
+		{"pow(true, 2)", "argument to `pow` must be INTEGER, got BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Package testutil has helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"fmt"
+	"monkey/token"
+	"testing"
+)
+
+// DiffTokens() compares two token streams and describes the first difference, or returns "" if there is none.
+// Types and literals are always compared; positions only when the expected token has one (a non-zero Line), so
+// expectations can leave positions out when they don't matter.
+
+func DiffTokens(expected, actual []token.Token) string {
+	for i := 0; i < len(expected) && i < len(actual); i++ {
+		if !tokenMatches(expected[i], actual[i]) {
+			return fmt.Sprintf("tokens[%d] differ.\nexpected=%s\ngot=     %s", i,
+				formatToken(expected[i]), formatToken(actual[i]))
+		}
+	}
+
+	switch {
+	case len(actual) > len(expected):
+		return fmt.Sprintf("got %d tokens, expected %d. first extra: tokens[%d]=%s", len(actual), len(expected),
+			len(expected), formatToken(actual[len(expected)]))
+	case len(actual) < len(expected):
+		return fmt.Sprintf("got %d tokens, expected %d. first missing: tokens[%d]=%s", len(actual), len(expected),
+			len(actual), formatToken(expected[len(actual)]))
+	}
+	return ""
+}
+
+// AssertTokens() fails the test if the token streams differ, reporting the first difference. The input the tokens
+// came from is included in the failure to tell table entries apart.
+
+func AssertTokens(t testing.TB, input string, expected, actual []token.Token) {
+	t.Helper()
+	if diff := DiffTokens(expected, actual); diff != "" {
+		t.Errorf("wrong tokens for %q: %s", input, diff)
+	}
+}
+
+func tokenMatches(expected, actual token.Token) bool {
+	if expected.Type != actual.Type || expected.Literal != actual.Literal {
+		return false
+	}
+	return expected.Line == 0 || expected.Line == actual.Line && expected.Column == actual.Column
+}
+
+func formatToken(tok token.Token) string {
+	if tok.Line == 0 {
+		return fmt.Sprintf("%s %q", tok.Type, tok.Literal)
+	}
+	return fmt.Sprintf("%s %q at %d:%d", tok.Type, tok.Literal, tok.Line, tok.Column)
+}
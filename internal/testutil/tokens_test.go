@@ -0,0 +1,54 @@
+package testutil
+
+import (
+	"monkey/token"
+	"testing"
+)
+
+func TestDiffTokens(t *testing.T) {
+	let := token.Token{Type: token.LET, Literal: "let", Line: 1, Column: 1}
+	x := token.Token{Type: token.IDENT, Literal: "x", Line: 1, Column: 5}
+	y := token.Token{Type: token.IDENT, Literal: "y", Line: 1, Column: 5}
+	eof := token.Token{Type: token.EOF, Literal: "", Line: 1, Column: 6}
+
+	tests := []struct {
+		expected []token.Token
+		actual   []token.Token
+		diff     string
+	}{
+		{[]token.Token{let, x, eof}, []token.Token{let, x, eof}, ""},
+		{nil, nil, ""},
+		{
+			[]token.Token{let, x, eof},
+			[]token.Token{let, y, eof},
+			"tokens[1] differ.\nexpected=IDENT \"x\" at 1:5\ngot=     IDENT \"y\" at 1:5",
+		},
+		{
+			// positions are only checked when the expectation has them
+			[]token.Token{{Type: token.LET, Literal: "let"}, {Type: token.IDENT, Literal: "x"}},
+			[]token.Token{let, x},
+			"",
+		},
+		{
+			[]token.Token{let, {Type: token.IDENT, Literal: "x", Line: 2, Column: 1}},
+			[]token.Token{let, x},
+			"tokens[1] differ.\nexpected=IDENT \"x\" at 2:1\ngot=     IDENT \"x\" at 1:5",
+		},
+		{
+			[]token.Token{let, x},
+			[]token.Token{let, x, eof},
+			"got 3 tokens, expected 2. first extra: tokens[2]=EOF \"\" at 1:6",
+		},
+		{
+			[]token.Token{let, {Type: token.IDENT, Literal: "x"}, eof},
+			[]token.Token{let},
+			"got 1 tokens, expected 3. first missing: tokens[1]=IDENT \"x\"",
+		},
+	}
+
+	for i, tt := range tests {
+		if diff := DiffTokens(tt.expected, tt.actual); diff != tt.diff {
+			t.Errorf("tests[%d] wrong diff.\nwant=%q\ngot= %q", i, tt.diff, diff)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package interp
+
+import (
+	"monkey/object"
+)
+
+// ToObject() converts a Go value into the corresponding Monkey object, see object.FromGo().
+
+func ToObject(v interface{}) (object.Object, error) {
+	return object.FromGo(v)
+}
+
+// FromObject() is the reverse of ToObject(), see object.ToGo().
+
+func FromObject(obj object.Object) (interface{}, error) {
+	return object.ToGo(obj)
+}
@@ -0,0 +1,166 @@
+// Package interp is the embedding API for Monkey: it wires the lexer, parser and evaluator together behind a single
+// Interpreter value, so host programs don't have to.
+package interp
+
+import (
+	"bytes"
+	"io"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"strings"
+)
+
+// Interpreter runs Monkey programs. All programs run by the same Interpreter share one global environment, so
+// bindings made by one Run are visible to the next, just like lines typed into the REPL.
+
+type Interpreter struct {
+	env     *object.Environment
+	options Options
+}
+
+// Options collects the optional language behaviors in one place. The zero value is standard Monkey, so only the
+// fields that should differ need to be set.
+
+type Options struct {
+	// CaseInsensitiveKeywords makes keywords match regardless of case, so `LET x = 5` is a let statement.
+	// Identifiers keep the spelling they were written with.
+	CaseInsensitiveKeywords bool
+
+	// Truthiness selects which values `if` and `!` treat as false. The zero value, evaluator.StrictTruthiness,
+	// treats only false and null as falsy; evaluator.ExtendedTruthiness also treats 0 as falsy.
+	Truthiness evaluator.TruthinessMode
+
+	// Output is where puts writes to. nil means os.Stdout.
+	Output io.Writer
+
+	// OutputLimit is how many bytes each Run may write to Output before puts fails with an "output limit exceeded"
+	// error. Zero means evaluator.DefaultOutputLimit.
+	OutputLimit int64
+
+	// MaxNestingDepth limits how deeply expressions and blocks may nest before the parser gives up. Zero means
+	// parser.DefaultMaxNestingDepth.
+	MaxNestingDepth int
+}
+
+func New() *Interpreter {
+	return NewWithOptions(Options{})
+}
+
+func NewWithOptions(options Options) *Interpreter {
+	return &Interpreter{env: object.NewEnvironment(), options: options}
+}
+
+func (i *Interpreter) lexerOptions() []lexer.Option {
+	var opts []lexer.Option
+	if i.options.CaseInsensitiveKeywords {
+		opts = append(opts, lexer.WithCaseInsensitiveKeywords())
+	}
+	return opts
+}
+
+func (i *Interpreter) parserOptions() []parser.Option {
+	var opts []parser.Option
+	if i.options.MaxNestingDepth > 0 {
+		opts = append(opts, parser.WithMaxNestingDepth(i.options.MaxNestingDepth))
+	}
+	return opts
+}
+
+// evaluatorConfig() returns the settings for a single Run or Call that prints to out.
+
+func (i *Interpreter) evaluatorConfig(out io.Writer) evaluator.Config {
+	return evaluator.Config{
+		Truthiness:  i.options.Truthiness,
+		Output:      out,
+		OutputLimit: i.options.OutputLimit,
+	}
+}
+
+// Run() parses and evaluates src and returns the value of the program: the value of a top-level return, or else the
+// value of the last statement, e.g. 3 for `1; 2; 3`. Statements that don't produce a value, like `let x = 5;`, and
+// empty programs yield NULL, so a successful Run never returns a nil object. Parser errors are returned as a
+// *ParseError and nothing is evaluated; an error produced while evaluating is returned as a *RuntimeError.
+
+func (i *Interpreter) Run(src string) (object.Object, error) {
+	return i.run(src, i.options.Output)
+}
+
+// run() is Run() with puts writing to out instead of the configured output.
+
+func (i *Interpreter) run(src string, out io.Writer) (object.Object, error) {
+	l := lexer.New(src, i.lexerOptions()...)
+	p := parser.New(l, i.parserOptions()...)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Messages: p.Errors()}
+	}
+
+	result := i.evaluatorConfig(out).Eval(program, i.env)
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, &RuntimeError{Err: errObj}
+	}
+	if result == nil {
+		return evaluator.NULL, nil
+	}
+
+	return result, nil
+}
+
+// RunCapture() is Run() with everything the program prints through puts captured in a string instead of going to the
+// configured output. It's meant for testing programs that produce output.
+
+func (i *Interpreter) RunCapture(src string) (object.Object, string, error) {
+	var buf bytes.Buffer
+	result, err := i.run(src, &buf)
+	return result, buf.String(), err
+}
+
+// Define() makes a host function available to Monkey programs under name. It's bound in the interpreter's global
+// environment, so it takes precedence over a builtin of the same name and can be redefined by the program.
+
+func (i *Interpreter) Define(name string, fn func(args ...object.Object) object.Object) {
+	i.env.Set(name, &object.Builtin{Fn: func(_ object.BuiltinContext, args ...object.Object) object.Object {
+		return fn(args...)
+	}})
+}
+
+// Get() returns the value bound to name in the interpreter's global environment.
+
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	return i.env.Get(name)
+}
+
+// Call() calls fn, typically a function fetched with Get(), with args. Use ToObject() to convert Go values into
+// arguments and FromObject() to convert the result back.
+
+func (i *Interpreter) Call(fn object.Object, args ...object.Object) (object.Object, error) {
+	result := i.evaluatorConfig(i.options.Output).Apply(fn, args)
+	if errObj, ok := result.(*object.Error); ok {
+		return nil, &RuntimeError{Err: errObj}
+	}
+	return result, nil
+}
+
+// ParseError holds every error the parser reported for a program.
+
+type ParseError struct {
+	Messages []string
+}
+
+func (e *ParseError) Error() string {
+	return "parser errors:\n\t" + strings.Join(e.Messages, "\n\t")
+}
+
+// RuntimeError is a Monkey error object that ended evaluation. Its message includes the error's causes, like the
+// error's Inspect() does.
+
+type RuntimeError struct {
+	Err *object.Error
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Err.Chain()
+}
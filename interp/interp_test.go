@@ -0,0 +1,371 @@
+package interp
+
+import (
+	"bytes"
+	"errors"
+	"monkey/evaluator"
+	"monkey/object"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	i := New()
+
+	result, err := i.Run("let add = fn(x, y) { x + y }; add(2, 3);")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 5)
+}
+
+func TestRunResult(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1; 2; 3", 3},
+		{"1; return 2; 3", 2},
+		{"let x = 5; x", 5},
+		{"if (true) { 1; 2 }", 2},
+		{"let x = 5;", nil},
+		{"5; let x = 6;", nil},
+		{"", nil},
+		{"if (false) { 1 }", nil},
+	}
+
+	for _, tt := range tests {
+		result, err := New().Run(tt.input)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", tt.input, err)
+			continue
+		}
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, result, int64(expected))
+		case nil:
+			if result != evaluator.NULL {
+				t.Errorf("result for %q is not NULL. got=%T (%+v)", tt.input, result, result)
+			}
+		}
+	}
+}
+
+func TestRunCapture(t *testing.T) {
+	i := New()
+
+	result, output, err := i.RunCapture("puts(1 + 2, true); puts(7); 42")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 42)
+
+	if output != "3\ntrue\n7\n" {
+		t.Errorf("wrong captured output. got=%q", output)
+	}
+
+	// the capture buffer isn't used after RunCapture returns
+	var buf bytes.Buffer
+	i = NewWithOptions(Options{Output: &buf})
+	if _, _, err := i.RunCapture("puts(1)"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := i.Run("puts(2)"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if buf.String() != "2\n" {
+		t.Errorf("wrong output after RunCapture. got=%q", buf.String())
+	}
+
+	// output of a Run happening at the same time doesn't end up in the capture
+	var out safeBuffer
+	i = NewWithOptions(Options{Output: &out})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for n := 0; n < 100; n++ {
+			i.Run("puts(2)")
+		}
+	}()
+	for n := 0; n < 100; n++ {
+		if _, output, _ := i.RunCapture("puts(1)"); output != "1\n" {
+			t.Fatalf("wrong captured output. got=%q", output)
+		}
+	}
+	<-done
+	if out.String() != strings.Repeat("2\n", 100) {
+		t.Errorf("wrong output next to RunCapture. got=%q", out.String())
+	}
+}
+
+// safeBuffer is a bytes.Buffer that can be written from several goroutines.
+
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRunPersistsState(t *testing.T) {
+	i := New()
+
+	if _, err := i.Run("let x = 5;"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := i.Run("let double = fn(n) { n * 2 };"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	result, err := i.Run("double(x)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 10)
+
+	// a fresh interpreter doesn't see any of it
+	if _, err := New().Run("x"); err == nil {
+		t.Fatalf("expected an error for x in a fresh interpreter")
+	}
+}
+
+func TestDefine(t *testing.T) {
+	i := New()
+
+	calls := 0
+	i.Define("triple", func(args ...object.Object) object.Object {
+		calls++
+		return &object.Integer{Value: args[0].(*object.Integer).Value * 3}
+	})
+
+	result, err := i.Run("triple(4) + triple(1)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 15)
+
+	if calls != 2 {
+		t.Errorf("host function called %d times, want 2", calls)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	i := New()
+
+	_, err := i.Run("let = 5;")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("error is not *ParseError. got=%T (%v)", err, err)
+	}
+	if len(parseErr.Messages) == 0 {
+		t.Errorf("ParseError has no messages")
+	}
+
+	_, err = i.Run("5 + true")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("error is not *RuntimeError. got=%T (%v)", err, err)
+	}
+	if runtimeErr.Err.Kind != object.TypeMismatch {
+		t.Errorf("wrong error kind. want=%s, got=%s", object.TypeMismatch, runtimeErr.Err.Kind)
+	}
+	if err.Error() != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error message. got=%q", err.Error())
+	}
+
+	// the message includes the causes of a wrapped error
+	i.Define("fail", func(args ...object.Object) object.Object {
+		return object.Wrap(&object.Error{Kind: object.DivByZero, Message: "division by zero"}, "in fail()")
+	})
+	if _, err = i.Run("fail()"); err == nil || err.Error() != "in fail(): division by zero" {
+		t.Errorf("wrong error message for a wrapped error. got=%v", err)
+	}
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf("object is not Integer. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%d, want=%d", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func TestOptions(t *testing.T) {
+	input := "If (0) { 1 } else { 2 }"
+
+	extended := NewWithOptions(Options{
+		CaseInsensitiveKeywords: true,
+		Truthiness:              evaluator.ExtendedTruthiness,
+	})
+
+	result, err := extended.Run(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 2)
+
+	// with the zero value `If` is an identifier, so the same source doesn't parse
+	if _, err := New().Run(input); err == nil {
+		t.Errorf("expected a parse error for %q with default options", input)
+	}
+
+	if _, err := NewWithOptions(Options{MaxNestingDepth: 2}).Run("((1))"); err == nil {
+		t.Errorf("expected a nesting error with MaxNestingDepth: 2")
+	}
+
+	// the limit is per Run, so a second Run may print again
+	var buf bytes.Buffer
+	limited := NewWithOptions(Options{Output: &buf, OutputLimit: 4})
+	if _, err := limited.Run("puts(12); puts(34)"); err == nil || err.Error() != "output limit exceeded" {
+		t.Errorf("expected an output limit error with OutputLimit: 4. got=%v", err)
+	}
+	if _, err := limited.Run("puts(5)"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if buf.String() != "12\n35\n" {
+		t.Errorf("wrong limited output. got=%q", buf.String())
+	}
+
+	result, err = New().Run("if (0) { 1 } else { 2 }")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testIntegerObject(t, result, 1)
+}
+
+func TestConcurrentInterpreters(t *testing.T) {
+	var strictOut, extendedOut bytes.Buffer
+	strict := NewWithOptions(Options{Output: &strictOut})
+	extended := NewWithOptions(Options{Output: &extendedOut, Truthiness: evaluator.ExtendedTruthiness})
+
+	// interpreters with different options can run at the same time without seeing each other's settings
+	run := func(i *Interpreter, expected int64, errs chan<- error) {
+		for n := 0; n < 100; n++ {
+			result, err := i.Run("puts(n); if (0) { 1 } else { 2 }")
+			if err == nil {
+				if integer, ok := result.(*object.Integer); !ok || integer.Value != expected {
+					err = errors.New("wrong result: " + result.Inspect())
+				}
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+		errs <- nil
+	}
+
+	strict.Run("let n = 1;")
+	extended.Run("let n = 2;")
+
+	errs := make(chan error)
+	go run(strict, 1, errs)
+	go run(extended, 2, errs)
+	for k := 0; k < 2; k++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+
+	if strictOut.String() != strings.Repeat("1\n", 100) {
+		t.Errorf("wrong output for the strict interpreter. got=%q", strictOut.String())
+	}
+	if extendedOut.String() != strings.Repeat("2\n", 100) {
+		t.Errorf("wrong output for the extended interpreter. got=%q", extendedOut.String())
+	}
+}
+
+func TestGetAndCall(t *testing.T) {
+	i := New()
+
+	if _, err := i.Run("let clamp = fn(x, lo, hi) { max(lo, min(x, hi)) };"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fn, ok := i.Get("clamp")
+	if !ok {
+		t.Fatalf("clamp is not defined")
+	}
+
+	args := []object.Object{}
+	for _, v := range []interface{}{42, int64(0), 10} {
+		arg, err := ToObject(v)
+		if err != nil {
+			t.Fatalf("unexpected error converting %v: %s", v, err)
+		}
+		args = append(args, arg)
+	}
+
+	result, err := i.Call(fn, args...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	value, err := FromObject(result)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if value != int64(10) {
+		t.Errorf("wrong result. want=10, got=%v (%T)", value, value)
+	}
+
+	if _, err := i.Call(fn, args[0]); err == nil {
+		t.Errorf("expected an arity error calling clamp with one argument")
+	}
+
+	if _, ok := i.Get("missing"); ok {
+		t.Errorf("Get returned ok for an unbound name")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []interface{}{7, int64(-3), true, false, nil}
+
+	for _, v := range tests {
+		obj, err := ToObject(v)
+		if err != nil {
+			t.Errorf("unexpected error converting %v: %s", v, err)
+			continue
+		}
+
+		back, err := FromObject(obj)
+		if err != nil {
+			t.Errorf("unexpected error converting %s back: %s", obj.Inspect(), err)
+			continue
+		}
+
+		expected := v
+		if i, ok := v.(int); ok {
+			expected = int64(i)
+		}
+		if back != expected {
+			t.Errorf("round trip of %v (%T) gave %v (%T)", v, v, back, back)
+		}
+	}
+
+	if _, err := ToObject(3.5); err == nil {
+		t.Errorf("expected an error converting a float64")
+	}
+	if _, err := FromObject(&object.Function{}); err == nil {
+		t.Errorf("expected an error converting a function")
+	}
+}
@@ -1,12 +1,61 @@
 package lexer
 
-import "monkey/token"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"monkey/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
 type Lexer struct {
 	input        string
+	offset       int  // where input starts in the whole input; only a lexer reading from an io.Reader drops its start
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
-	ch           byte // current char under examination
+	ch           rune // current char under examination, decoded from UTF-8
+	line         int  // line of the current char, starting at 1
+	column       int  // column of the current char within its line, starting at 1 (counted in characters)
+
+	caseInsensitiveKeywords bool // match keywords regardless of case, see WithCaseInsensitiveKeywords()
+	newlineTokens           bool // emit line breaks as NEWLINE tokens, see WithNewlineTokens()
+
+	unread    token.Token // a token pushed back with Unread(), returned by the next NextToken()
+	hasUnread bool
+
+	peeked    token.Token // the token scanned ahead by PeekToken(), returned after any unread token
+	hasPeeked bool
+	peekedAt  int // Position() from before the peeked token was scanned
+
+	reader   io.Reader // where the rest of the input comes from, see NewFromReader(); nil once it's used up
+	err      error     // the error reading from reader failed with, see Err()
+	marked   bool      // Mark() has been called, so the input from markedAt on has to be kept
+	markedAt int
+}
+
+// ErrUnreadFull is returned by Unread() when there already is a pushed-back token.
+var ErrUnreadFull = errors.New("lexer: a token has already been unread")
+
+// Option configures optional lexer behavior. Options are passed to New(); without any, the lexer behaves exactly like
+// the standard Monkey lexer.
+
+type Option func(*Lexer)
+
+// WithCaseInsensitiveKeywords() makes keywords case-insensitive, so `LET x = 5` lexes like `let x = 5`. Identifier
+// literals are left unchanged.
+
+func WithCaseInsensitiveKeywords() Option {
+	return func(l *Lexer) { l.caseInsensitiveKeywords = true }
+}
+
+// WithNewlineTokens() makes every line break a NEWLINE token instead of whitespace, for experimenting with
+// newline-terminated statements. A \r\n pair is one line break. Line breaks escaped with a backslash and those inside
+// block comments are still skipped.
+
+func WithNewlineTokens() Option {
+	return func(l *Lexer) { l.newlineTokens = true }
 }
 
 // New() is a constructor function that returns a new lexer. It initializes the lexer by setting the input string and
@@ -15,149 +64,644 @@ type Lexer struct {
 // call to readChar() sets both l.ch and l.readPosition, while the second one advances those fields to their correct
 // values. After these two calls, we can call NextToken() and get the first token from our input string.
 
-func New(input string) *Lexer {
-	l := &Lexer{input: input} // create a new Lexer (a pointer to a Lexer) by passing in the input string
-	l.readChar()              // sets l.ch and l.readPosition
+func New(input string, opts ...Option) *Lexer {
+	l := &Lexer{input: input, line: 1} // create a new Lexer (a pointer to a Lexer) by passing in the input string
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.readChar() // sets l.ch and l.readPosition
 	return l
 }
 
+// NewFromReader() is New() for input that comes from an io.Reader, like a file or a pipe. The input is read as it's
+// needed, and whatever has been lexed is dropped, so only the token being read and a small buffer after it are held in
+// memory. An error from the first read is returned as is; one that happens later ends the input, and Err() reports it.
+
+func NewFromReader(r io.Reader, opts ...Option) (*Lexer, error) {
+	l := &Lexer{line: 1, reader: r}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.readChar()
+	if l.err != nil {
+		return nil, l.err
+	}
+	return l, nil
+}
+
+// Err() returns the error reading the input of a lexer made with NewFromReader() failed with, or nil if there was
+// none. The lexer treats such an error as the end of the input, so a caller that gets EOF should check Err().
+
+func (l *Lexer) Err() error {
+	return l.err
+}
+
+// readSize is how many bytes a lexer made with NewFromReader() reads at least at a time.
+const readSize = 4096
+
+// fill() reads more of the input from the reader and reports whether it got any.
+
+func (l *Lexer) fill() bool {
+	if l.reader == nil {
+		return false
+	}
+
+	// reading at least as much as is buffered keeps the copying linear in the length of a long token
+	buf := make([]byte, max(readSize, len(l.input)-l.position))
+	n, err := l.reader.Read(buf)
+	for n == 0 && err == nil {
+		n, err = l.reader.Read(buf)
+	}
+	if err != nil {
+		l.reader = nil
+		if err != io.EOF {
+			l.err = err
+		}
+	}
+
+	l.input += string(buf[:n])
+	return n > 0
+}
+
+// need() makes sure the character at l.readPosition is in the buffer, unless the input ends before it.
+
+func (l *Lexer) need() {
+	for l.reader != nil && !utf8.FullRuneInString(l.input[l.readPosition:]) && l.fill() {
+	}
+}
+
+// discard() drops the part of the buffer that has been lexed. It's only called between tokens, since the functions
+// reading a token slice its literal out of the buffer.
+
+func (l *Lexer) discard() {
+	if l.reader == nil {
+		return
+	}
+	keep := l.position
+	if l.marked {
+		keep = min(keep, l.markedAt-l.offset)
+	}
+	l.input = l.input[keep:]
+	l.offset += keep
+	l.position -= keep
+	l.readPosition -= keep
+}
+
+// Tokenize() lexes the whole input in one go. It returns every token up to and including the EOF token, plus an error
+// for each ILLEGAL token encountered along the way, e.g. `illegal token "0x" at 1:9`. An ILLEGAL token can be longer
+// than one character, like an unterminated string or a malformed number, so the error quotes the whole token.
+
+func Tokenize(input string) ([]token.Token, []error) {
+	tokens := New(input).Tokenize()
+
+	var errs []error
+	for _, tok := range tokens {
+		if tok.Type == token.ILLEGAL {
+			errs = append(errs, fmt.Errorf("illegal token %q at %d:%d", tok.Literal, tok.Line, tok.Column))
+		}
+	}
+
+	return tokens, errs
+}
+
+// Tokenize() returns all the tokens left in the input, up to and including the EOF token. On a lexer that has already
+// returned EOF, that's just another EOF.
+
+func (l *Lexer) Tokenize() []token.Token {
+	var tokens []token.Token
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
+// Clone() returns a copy of the lexer with identical internal state. Because the input string is immutable, the copy is
+// cheap and fully independent: a caller can scan ahead on the clone and simply discard it without affecting the
+// original lexer. A lexer made with NewFromReader() can't share its reader, so it reads the rest of the input first.
+func (l *Lexer) Clone() *Lexer {
+	for l.fill() {
+	}
+	clone := *l
+	return &clone
+}
+
+// LexerState is a snapshot of where a lexer is in its input, taken with Mark() and restored with Reset(). It's opaque
+// on purpose: the only thing to do with one is hand it back to the lexer it came from.
+
+type LexerState struct {
+	position     int // in the whole input, like Position()
+	readPosition int
+	ch           rune
+	line         int
+	column       int
+
+	unread    token.Token
+	hasUnread bool
+	peeked    token.Token
+	hasPeeked bool
+	peekedAt  int
+}
+
+// Mark() saves the lexer's current state, unread and peeked tokens included, so a parser can try one way of reading
+// the input and Reset() to the mark if it doesn't work out. Unlike Clone() it doesn't allocate. A lexer made with
+// NewFromReader() keeps all of its input from the first mark on, since it can't tell when a mark is no longer needed.
+
+func (l *Lexer) Mark() LexerState {
+	if !l.marked {
+		l.marked = true
+		l.markedAt = l.offset + l.position
+	}
+	return LexerState{
+		position:     l.offset + l.position,
+		readPosition: l.offset + l.readPosition,
+		ch:           l.ch,
+		line:         l.line,
+		column:       l.column,
+		unread:       l.unread,
+		hasUnread:    l.hasUnread,
+		peeked:       l.peeked,
+		hasPeeked:    l.hasPeeked,
+		peekedAt:     l.peekedAt,
+	}
+}
+
+// Reset() rewinds the lexer to a state returned by Mark(). The tokens read after the mark are then returned again,
+// identically.
+
+func (l *Lexer) Reset(state LexerState) {
+	l.position = state.position - l.offset
+	l.readPosition = state.readPosition - l.offset
+	l.ch = state.ch
+	l.line = state.line
+	l.column = state.column
+	l.unread = state.unread
+	l.hasUnread = state.hasUnread
+	l.peeked = state.peeked
+	l.hasPeeked = state.hasPeeked
+	l.peekedAt = state.peekedAt
+}
+
+// Position() returns the byte offset in the input where scanning will resume, i.e. just past the last token returned
+// by NextToken(). At the end of the input it's len(input). Together with the position before a NextToken() call this
+// lets a driver slice the raw source of a token (or of a whole node) out of the input.
+
+func (l *Lexer) Position() int {
+	if l.hasPeeked {
+		return l.peekedAt // a peeked token hasn't been returned yet
+	}
+	if l.position > len(l.input) {
+		return l.offset + len(l.input) // NextToken() keeps advancing past the end once it has returned EOF
+	}
+	return l.offset + l.position
+}
+
 func (l *Lexer) readChar() {
 	// If we reach the end of the input, we set ch to 0, which is the ASCII code for the "NUL" character and has no
 	// visible representation. We do this instead of returning an error or throwing an exception because we want our
 	// lexer to always return a character. This way, our parser can always make progress in the input string and never
 	// has to handle errors or exceptions.
 
+	// The character we're moving past decides where the next one is: after a line break, it starts the next line.
+	// Windows (\r\n) and old Mac (\r) line endings count as one line break each, so they give the same positions as \n.
+	if l.ch == '\n' || l.ch == '\r' && l.peekChar() != '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
+	l.need()
+	width := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		// Otherwise, we read the next character and advance our position in the input string.
-		l.ch = l.input[l.readPosition]
+		// Otherwise, we read the next character and advance our position in the input string. A character can take
+		// up to 4 bytes; invalid UTF-8 is read one byte at a time as utf8.RuneError.
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition // l.position always points where we last read
-	l.readPosition += 1         // l.readPosition always points to the next character
+	l.readPosition += width     // l.readPosition always points to the next character
+}
+
+// Unread() pushes tok back, so the next call to NextToken() returns it before scanning resumes. There's room for one
+// token only: unreading a second one before it has been read again fails with ErrUnreadFull. Position() isn't moved
+// back; it still points past the token that was read.
+
+func (l *Lexer) Unread(tok token.Token) error {
+	if l.hasUnread {
+		return ErrUnreadFull
+	}
+	l.unread = tok
+	l.hasUnread = true
+	return nil
 }
 
 // NextToken() is the heart of our lexer. It's responsible for both reading a character from the input and returning
 // the next token. It's also responsible for advancing our two pointers l.position and l.readPosition.
 
 func (l *Lexer) NextToken() token.Token {
+	if l.hasUnread {
+		l.hasUnread = false
+		return l.unread
+	}
+	if l.hasPeeked {
+		l.hasPeeked = false
+		return l.peeked
+	}
+
+	return l.scanToken()
+}
+
+// PeekToken() returns the token the next NextToken() call will return, without consuming it. Peeking any number of
+// times in a row returns the same token, positions included, and the token is scanned only once. Position() isn't moved
+// by peeking.
+
+func (l *Lexer) PeekToken() token.Token {
+	if l.hasUnread {
+		return l.unread
+	}
+	if !l.hasPeeked {
+		l.peekedAt = l.Position()
+		l.peeked = l.scanToken()
+		l.hasPeeked = true
+	}
+	return l.peeked
+}
+
+// scanToken() reads the next token from the input, ignoring the unread and peeked tokens.
+
+func (l *Lexer) scanToken() token.Token {
 	var tok token.Token
 
 	// We skip over any whitespace characters by calling l.skipWhitespace().
 	l.skipWhitespace()
 
+	// Every token is reported at the position of its first character.
+	line, column := l.line, l.column
+
+	// Characters that are a token on their own, whatever comes next, are simply looked up.
+	if l.ch < utf8.RuneSelf && singleCharTokens[l.ch].Type != "" {
+		tok = singleCharTokens[l.ch]
+		l.readChar()
+		tok.Line, tok.Column = line, column
+		return tok
+	}
+
 	switch l.ch {
 	case '=':
-		if l.peekChar() == '=' {
-			ch := l.ch // save the current character
-			l.readChar()
-			literal := string(ch) + string(l.ch)                // create a new literal
-			tok = token.Token{Type: token.EQ, Literal: literal} // create a new token
-		} else {
-			tok = newToken(token.ASSIGN, l.ch)
-		}
-
+		tok = l.readOneOrTwoCharToken('=', token.ASSIGN, token.EQ)
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		tok = l.readOneOrTwoCharToken('=', token.PLUS, token.PLUS_ASSIGN)
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
-	case '!':
-		if l.peekChar() == '=' {
-			ch := l.ch // save the current character
-			l.readChar()
-			literal := string(ch) + string(l.ch)                    // create a new literal
-			tok = token.Token{Type: token.NOT_EQ, Literal: literal} // create a new token
-		} else {
-			tok = newToken(token.BANG, l.ch)
-		}
-	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		tok = l.readOneOrTwoCharToken('=', token.MINUS, token.MINUS_ASSIGN)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		tok = l.readOneOrTwoCharToken('=', token.ASTERISK, token.ASTERISK_ASSIGN)
+
+	case '!':
+		tok = l.readOneOrTwoCharToken('=', token.BANG, token.NOT_EQ)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		switch l.peekChar() {
+		case '<':
+			tok = l.readTwoCharToken(token.SHL)
+		case '=':
+			tok = l.readTwoCharToken(token.LT_EQ)
+		default:
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
-	case ';':
-		tok = newToken(token.SEMICOLON, l.ch)
-	case '(':
-		tok = newToken(token.LPAREN, l.ch)
-	case ')':
-		tok = newToken(token.RPAREN, l.ch)
-	case ',':
-		tok = newToken(token.COMMA, l.ch)
-	case '{':
-		tok = newToken(token.LBRACE, l.ch)
-	case '}':
-		tok = newToken(token.RBRACE, l.ch)
+		switch l.peekChar() {
+		case '>':
+			tok = l.readTwoCharToken(token.SHR)
+		case '=':
+			tok = l.readTwoCharToken(token.GT_EQ)
+		default:
+			tok = newToken(token.GT, l.ch)
+		}
+	case '&':
+		tok = l.readOneOrTwoCharToken('&', token.BIT_AND, token.AND)
+	case '|':
+		tok = l.readOneOrTwoCharToken('|', token.BIT_OR, token.OR)
+	case '/':
+		if l.peekChar() == '*' {
+			// skipWhitespace() leaves a block comment here only if it's never closed
+			position := l.position
+			for l.ch != 0 {
+				l.readChar()
+			}
+			tok = token.Token{Type: token.ILLEGAL, Literal: l.input[position:l.position]}
+		} else {
+			tok = l.readOneOrTwoCharToken('=', token.SLASH, token.SLASH_ASSIGN)
+		}
+	case '"', '`':
+		tok.Type, tok.Literal = l.readString(l.ch)
+	case '\n': // skipWhitespace() leaves line breaks here only with WithNewlineTokens()
+		tok = newToken(token.NEWLINE, l.ch)
+	case '\r':
+		tok = l.readOneOrTwoCharToken('\n', token.NEWLINE, token.NEWLINE)
 	case 0: // 0 is the ASCII code for the "NUL" character and has no visible representation
 		tok.Literal = ""
 		tok.Type = token.EOF
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
-			tok.Type = token.LookupIdent(tok.Literal) // check if the identifier is a keyword
+			tok.Type = l.lookupIdent(tok.Literal) // check if the identifier is a keyword
+			tok.Line, tok.Column = line, column
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber() // readNumber() advances l.position and l.readPosition
+			tok.Type, tok.Literal = l.readNumber() // readNumber() advances l.position and l.readPosition
+			tok.Line, tok.Column = line, column
 			return tok
 		} else {
-			tok = newToken(token.ILLEGAL, l.ch)
+			// the literal is sliced from the input, so it's the offending bytes even if they aren't valid UTF-8
+			tok = token.Token{Type: token.ILLEGAL, Literal: l.input[l.position:l.readPosition]}
 		}
 	}
 	l.readChar()
+	tok.Line, tok.Column = line, column
 	return tok
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+// charLiterals caches the one-character string for every character below 256. Converting a character with string(ch)
+// allocates a new string for every operator and delimiter we emit; looking it up here doesn't.
+
+var charLiterals [256]string
+
+func init() {
+	for i := range charLiterals {
+		charLiterals[i] = string(rune(i))
+	}
+}
+
+// readOneOrTwoCharToken() lexes a character that is one token by itself and another when it's followed by second, like
+// `&` and `&&`. The two-character literal is sliced from the input, so it doesn't allocate either.
+
+func (l *Lexer) readOneOrTwoCharToken(second rune, single, double token.TokenType) token.Token {
+	if l.peekChar() != second {
+		return newToken(single, l.ch)
+	}
+	return l.readTwoCharToken(double)
+}
+
+// readTwoCharToken() lexes the current and the next character as one token of the given type.
+
+func (l *Lexer) readTwoCharToken(tokenType token.TokenType) token.Token {
+	position := l.position
+	l.readChar()
+	return token.Token{Type: tokenType, Literal: l.input[position:l.readPosition]}
+}
+
+// singleCharTokens holds the finished token for each character that always makes up a token by itself, so NextToken()
+// doesn't have to go through its switch for them. Characters that can start a longer token, like `=` in `==`, aren't
+// in here.
+
+var singleCharTokens [utf8.RuneSelf]token.Token
+
+func init() {
+	for ch, tokenType := range map[byte]token.TokenType{
+		'%': token.MODULO,
+		';': token.SEMICOLON,
+		':': token.COLON,
+		'.': token.DOT,
+		'(': token.LPAREN,
+		')': token.RPAREN,
+		',': token.COMMA,
+		'@': token.AT,
+		'^': token.BIT_XOR,
+		'~': token.BIT_NOT,
+		'{': token.LBRACE,
+		'}': token.RBRACE,
+		'[': token.LBRACKET,
+		']': token.RBRACKET,
+	} {
+		singleCharTokens[ch] = newToken(tokenType, rune(ch))
+	}
+}
+
+func (l *Lexer) lookupIdent(ident string) token.TokenType {
+	if l.caseInsensitiveKeywords {
+		return token.LookupIdentCaseInsensitive(ident)
+	}
+	return token.LookupIdent(ident)
+}
+
+func newToken(tokenType token.TokenType, ch rune) token.Token {
+	if ch < rune(len(charLiterals)) {
+		return token.Token{Type: tokenType, Literal: charLiterals[ch]}
+	}
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
-// readIdentifier() reads in an identifier and advances the lexer's position until it encounters a non-letter character.
-// It assumes that the current character is a letter and reads until it encounters a non-letter character. It then
-// returns the substring from l.position to l.readPosition. We use this function to read in keywords and identifiers.
+// readIdentifier() reads in an identifier and advances the lexer's position until it encounters a character that
+// can't be part of one. It assumes that the current character is a letter; after that, digits are allowed too, so
+// `value2` is one identifier. It then returns the substring from l.position to l.readPosition. We use this function to
+// read in keywords and identifiers.
 
 func (l *Lexer) readIdentifier() string {
-	position := l.position // save the current position in the input string
-	for isLetter(l.ch) {   // read until we encounter a non-letter character
+	position := l.position  // save the current position in the input string
+	for isIdentChar(l.ch) { // read until we encounter a character that can't be part of an identifier
 		l.readChar()
 	}
 	return l.input[position:l.position] // return the substring from position to l.position
 }
 
-func isLetter(ch byte) bool {
-	// We only support ASCII characters for now. We can easily extend this to support Unicode characters by using
-	// unicode.IsLetter() instead of our own isLetter() function.
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	// Identifiers can use letters from any script, like `café` or `λ`. ASCII is checked first, since it's by far
+	// the most common case and much cheaper than unicode.IsLetter().
+	if ch < utf8.RuneSelf {
+		return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+	}
+	return unicode.IsLetter(ch)
+}
+
+// isIdentChar() reports whether ch can appear in an identifier after its first character: a letter, `_` or a digit.
+
+func isIdentChar(ch rune) bool {
+	return isLetter(ch) || isDigit(ch)
 }
 
-func isDigit(ch byte) bool {
-	// We only support ASCII characters for now. We can easily extend this to support Unicode characters by using
-	// unicode.IsDigit() instead of our own isDigit() function.
+func isDigit(ch rune) bool {
+	// Numbers are ASCII only: digits from other scripts, which unicode.IsDigit() would accept, don't make numbers.
 	return '0' <= ch && ch <= '9'
 }
 
-// Note that we ignore floating point numbers, hexadecimal numbers, and so on. We only support integers for now.
+// readString() reads a string delimited by quote, either a double-quoted string or a raw string between backticks,
+// and returns its contents without the quotes. The contents are taken verbatim, so a raw string can span lines and
+// keeps a `\n` as a backslash and an n. It stops with l.ch on the closing quote, which NextToken() then skips like any
+// other single-character token. A string that is still open at the end of the input is ILLEGAL; its literal is
+// everything from the opening quote on, so it's easy to spot.
+
+func (l *Lexer) readString(quote rune) (token.TokenType, string) {
+	position := l.position // the opening quote
+	for {
+		l.readChar()
+		if l.ch == quote {
+			return token.STRING, l.input[position+1 : l.position]
+		}
+		if l.ch == 0 {
+			return token.ILLEGAL, l.input[position:l.position]
+		}
+	}
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func isOctalDigit(ch rune) bool {
+	return '0' <= ch && ch <= '7'
+}
+
+func isBinaryDigit(ch rune) bool {
+	return ch == '0' || ch == '1'
+}
+
+// readNumber() reads an integer, or a float if the digits are followed by a decimal point and more digits. Both sides
+// of the point need at least one digit: in `5.` the number ends before the point, and `.5` doesn't start a number at
+// all, so the point is left for NextToken() to deal with in both cases.
 
-func (l *Lexer) readNumber() string {
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	position := l.position // save the current position in the input string
-	for isDigit(l.ch) {    // read until we encounter a non-digit character
+	if l.ch == '0' {
+		switch l.peekChar() {
+		case 'x', 'X':
+			return l.readPrefixedNumber(isHexDigit)
+		case 'o', 'O':
+			return l.readPrefixedNumber(isOctalDigit)
+		case 'b', 'B':
+			return l.readPrefixedNumber(isBinaryDigit)
+		}
+	}
+	var tokenType token.TokenType = token.INT
+	valid := l.readDigits(isDigit)
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar() // the decimal point
+		valid = l.readDigits(isDigit) && valid
+	}
+
+	if !valid {
+		return token.ILLEGAL, l.input[position:l.position]
+	}
+	return tokenType, l.input[position:l.position] // return the substring from position to l.position
+}
+
+// readPrefixedNumber() reads a hexadecimal, octal or binary integer like `0xFF`, `0o755` or `0b1010`, where isValid
+// tells which digits the base allows. The literal keeps its prefix, which is what tells the parser (via
+// strconv.ParseInt with base 0) which base to read it in. A prefix without any digits after it is ILLEGAL, and so is
+// a number that runs into a digit its base doesn't have: `0b12` is one ILLEGAL token, not `0b1` followed by `2`.
+
+func (l *Lexer) readPrefixedNumber(isValid func(rune) bool) (token.TokenType, string) {
+	position := l.position
+	l.readChar() // the 0
+	l.readChar() // the x, o or b
+
+	if !isValid(l.ch) && !isDigit(l.ch) {
+		return token.ILLEGAL, l.input[position:l.position]
+	}
+	valid := l.readDigits(isValid)
+	if isDigit(l.ch) {
+		l.readDigits(isDigit)
+		valid = false
+	}
+
+	if !valid {
+		return token.ILLEGAL, l.input[position:l.position]
+	}
+	return token.INT, l.input[position:l.position]
+}
+
+// readDigits() reads a run of digits, where isValid tells which digits count, together with any underscores used as
+// separators in it, like `1_000_000`. The underscores are kept in the literal; strconv.ParseInt with base 0 skips them.
+// It reports false if an underscore isn't between two digits, as in `100_` or `1__0`.
+
+func (l *Lexer) readDigits(isValid func(rune) bool) bool {
+	valid := true
+	var prev rune
+	for isValid(l.ch) || l.ch == '_' { // read until we encounter a character that can't be part of the number
+		if l.ch == '_' && (!isValid(prev) || !isValid(l.peekChar())) {
+			valid = false
+		}
+		prev = l.ch
 		l.readChar()
 	}
-	return l.input[position:l.position] // return the substring from position to l.position
+	return valid
 }
 
+// skipWhitespace() also skips line continuations: a backslash immediately followed by a line break is whitespace, so
+// a long expression can be split across lines. A backslash anywhere else is left for NextToken() to reject. Comments,
+// from `//` to the end of the line or from `/*` to the next `*/`, count as whitespace too. A block comment that is never
+// closed is left for NextToken() as well, so it can be reported.
+
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' || l.ch == '\n' { // skip whitespace characters
+	for {
+		l.discard()
+		switch {
+		case l.ch == ' ' || l.ch == '\t': // skip whitespace characters
+			l.readChar()
+		case (l.ch == '\r' || l.ch == '\n') && !l.newlineTokens:
+			l.readChar()
+		case l.ch == '\\' && l.peekChar() == '\n':
+			l.readChar()
+			l.readChar()
+		case l.ch == '\\' && l.peekChar() == '\r':
+			l.readChar()
+			l.readChar()
+			if l.ch == '\n' {
+				l.readChar()
+			}
+		case l.ch == '/' && l.peekChar() == '/':
+			l.skipComment()
+		case l.ch == '/' && l.peekChar() == '*' && l.blockCommentClosed():
+			l.skipBlockComment()
+		default:
+			return
+		}
+	}
+}
+
+// skipComment() skips a `//` comment up to, but not including, the line break that ends it.
+
+func (l *Lexer) skipComment() {
+	for l.ch != '\n' && l.ch != '\r' && l.ch != 0 {
 		l.readChar()
 	}
 }
 
-func (l *Lexer) peekChar() byte {
+// blockCommentClosed() reports whether the block comment starting at the current character is closed somewhere in the
+// rest of the input.
+
+func (l *Lexer) blockCommentClosed() bool {
+	from := l.readPosition + 1 // past the *
+	for !strings.Contains(l.input[from:], "*/") {
+		from = max(from, len(l.input)-1) // the * of a */ may already be in
+		if !l.fill() {
+			return false
+		}
+	}
+	return true
+}
+
+// skipBlockComment() skips a `/* */` comment, which may span lines. It's only called when the comment is closed.
+// Comments don't nest: the first `*/` ends it.
+
+func (l *Lexer) skipBlockComment() {
+	l.readChar() // the /
+	l.readChar() // the *
+	for !(l.ch == '*' && l.peekChar() == '/') {
+		l.readChar() // going through readChar() keeps the line count right
+	}
+	l.readChar() // the *
+	l.readChar() // the /
+}
+
+func (l *Lexer) peekChar() rune {
+	l.need()
 	if l.readPosition >= len(l.input) { // if we reach the end of the input
 		return 0
-	} else {
-		return l.input[l.readPosition] // return the next character
 	}
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:]) // return the next character
+	return ch
 }
@@ -1,8 +1,13 @@
 package lexer
 
 import (
+	"errors"
+	"io"
+	"monkey/internal/testutil"
 	"monkey/token"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestNextToken(t *testing.T) {
@@ -14,7 +19,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -121,3 +126,1121 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestModuloToken(t *testing.T) {
+	input := "7 % 3; a%b"
+	expected := []token.Token{
+		{Type: token.INT, Literal: "7"},
+		{Type: token.MODULO, Literal: "%"},
+		{Type: token.INT, Literal: "3"},
+		{Type: token.SEMICOLON, Literal: ";"},
+		{Type: token.IDENT, Literal: "a"},
+		{Type: token.MODULO, Literal: "%"},
+		{Type: token.IDENT, Literal: "b"},
+		{Type: token.EOF, Literal: ""},
+	}
+
+	testutil.AssertTokens(t, input, expected, New(input).Tokenize())
+}
+
+func TestBitwiseTokens(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"a & b", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.BIT_AND, Literal: "&"}, {Type: token.IDENT, Literal: "b"}}},
+		{"a | b", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.BIT_OR, Literal: "|"}, {Type: token.IDENT, Literal: "b"}}},
+		{"a ^ b", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.BIT_XOR, Literal: "^"}, {Type: token.IDENT, Literal: "b"}}},
+		{"~a", []token.Token{{Type: token.BIT_NOT, Literal: "~"}, {Type: token.IDENT, Literal: "a"}}},
+		{"a && b", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.AND, Literal: "&&"}, {Type: token.IDENT, Literal: "b"}}},
+		{"a || b", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.OR, Literal: "||"}, {Type: token.IDENT, Literal: "b"}}},
+		{"&&&", []token.Token{{Type: token.AND, Literal: "&&"}, {Type: token.BIT_AND, Literal: "&"}}},
+		{"& &", []token.Token{{Type: token.BIT_AND, Literal: "&"}, {Type: token.BIT_AND, Literal: "&"}}},
+		{"|||", []token.Token{{Type: token.OR, Literal: "||"}, {Type: token.BIT_OR, Literal: "|"}}},
+		{"&|", []token.Token{{Type: token.BIT_AND, Literal: "&"}, {Type: token.BIT_OR, Literal: "|"}}},
+		{"^^~~", []token.Token{
+			{Type: token.BIT_XOR, Literal: "^"},
+			{Type: token.BIT_XOR, Literal: "^"},
+			{Type: token.BIT_NOT, Literal: "~"},
+			{Type: token.BIT_NOT, Literal: "~"},
+		}},
+	}
+
+	for _, tt := range tests {
+		expected := append(tt.expected, token.Token{Type: token.EOF, Literal: ""})
+		testutil.AssertTokens(t, tt.input, expected, New(tt.input).Tokenize())
+	}
+}
+
+func TestCompoundAssignmentTokens(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"x += 1", []token.Token{{Type: token.IDENT, Literal: "x"}, {Type: token.PLUS_ASSIGN, Literal: "+="}, {Type: token.INT, Literal: "1"}}},
+		{"x -= 1", []token.Token{{Type: token.IDENT, Literal: "x"}, {Type: token.MINUS_ASSIGN, Literal: "-="}, {Type: token.INT, Literal: "1"}}},
+		{"x *= 2", []token.Token{{Type: token.IDENT, Literal: "x"}, {Type: token.ASTERISK_ASSIGN, Literal: "*="}, {Type: token.INT, Literal: "2"}}},
+		{"x /= 2", []token.Token{{Type: token.IDENT, Literal: "x"}, {Type: token.SLASH_ASSIGN, Literal: "/="}, {Type: token.INT, Literal: "2"}}},
+		{"x + = 1", []token.Token{
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.PLUS, Literal: "+"},
+			{Type: token.ASSIGN, Literal: "="},
+			{Type: token.INT, Literal: "1"},
+		}},
+		{"x+==1", []token.Token{
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.PLUS_ASSIGN, Literal: "+="},
+			{Type: token.ASSIGN, Literal: "="},
+			{Type: token.INT, Literal: "1"},
+		}},
+		{"a == b != c", []token.Token{
+			{Type: token.IDENT, Literal: "a"},
+			{Type: token.EQ, Literal: "=="},
+			{Type: token.IDENT, Literal: "b"},
+			{Type: token.NOT_EQ, Literal: "!="},
+			{Type: token.IDENT, Literal: "c"},
+		}},
+		{"x /= 2 // halve", []token.Token{{Type: token.IDENT, Literal: "x"}, {Type: token.SLASH_ASSIGN, Literal: "/="}, {Type: token.INT, Literal: "2"}}},
+		{"-=-", []token.Token{{Type: token.MINUS_ASSIGN, Literal: "-="}, {Type: token.MINUS, Literal: "-"}}},
+	}
+
+	for _, tt := range tests {
+		expected := append(tt.expected, token.Token{Type: token.EOF, Literal: ""})
+		testutil.AssertTokens(t, tt.input, expected, New(tt.input).Tokenize())
+	}
+}
+
+func TestLogicalTokens(t *testing.T) {
+	input := "true && false || !x &y|z"
+	expected := []token.Token{
+		{Type: token.TRUE, Literal: "true", Line: 1, Column: 1},
+		{Type: token.AND, Literal: "&&", Line: 1, Column: 6},
+		{Type: token.FALSE, Literal: "false", Line: 1, Column: 9},
+		{Type: token.OR, Literal: "||", Line: 1, Column: 15},
+		{Type: token.BANG, Literal: "!", Line: 1, Column: 18},
+		{Type: token.IDENT, Literal: "x", Line: 1, Column: 19},
+		{Type: token.BIT_AND, Literal: "&", Line: 1, Column: 21},
+		{Type: token.IDENT, Literal: "y", Line: 1, Column: 22},
+		{Type: token.BIT_OR, Literal: "|", Line: 1, Column: 23},
+		{Type: token.IDENT, Literal: "z", Line: 1, Column: 24},
+		{Type: token.EOF, Literal: "", Line: 1, Column: 25},
+	}
+
+	testutil.AssertTokens(t, input, expected, New(input).Tokenize())
+}
+
+func TestShiftAndComparisonTokens(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"1 << 4", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.SHL, Literal: "<<"}, {Type: token.INT, Literal: "4"}}},
+		{"256 >> 2", []token.Token{{Type: token.INT, Literal: "256"}, {Type: token.SHR, Literal: ">>"}, {Type: token.INT, Literal: "2"}}},
+		{"a < b", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.LT, Literal: "<"}, {Type: token.IDENT, Literal: "b"}}},
+		{"a > b", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.GT, Literal: ">"}, {Type: token.IDENT, Literal: "b"}}},
+		{"< <", []token.Token{{Type: token.LT, Literal: "<"}, {Type: token.LT, Literal: "<"}}},
+		{"<<<", []token.Token{{Type: token.SHL, Literal: "<<"}, {Type: token.LT, Literal: "<"}}},
+		{"<>", []token.Token{{Type: token.LT, Literal: "<"}, {Type: token.GT, Literal: ">"}}},
+		{">>>>", []token.Token{{Type: token.SHR, Literal: ">>"}, {Type: token.SHR, Literal: ">>"}}},
+		{"a <= b", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.LT_EQ, Literal: "<="}, {Type: token.IDENT, Literal: "b"}}},
+		{"a >= b", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.GT_EQ, Literal: ">="}, {Type: token.IDENT, Literal: "b"}}},
+		{"<<=", []token.Token{{Type: token.SHL, Literal: "<<"}, {Type: token.ASSIGN, Literal: "="}}},
+		{"<==", []token.Token{{Type: token.LT_EQ, Literal: "<="}, {Type: token.ASSIGN, Literal: "="}}},
+		{">=>", []token.Token{{Type: token.GT_EQ, Literal: ">="}, {Type: token.GT, Literal: ">"}}},
+		{"< =", []token.Token{{Type: token.LT, Literal: "<"}, {Type: token.ASSIGN, Literal: "="}}},
+	}
+
+	for _, tt := range tests {
+		expected := append(tt.expected, token.Token{Type: token.EOF, Literal: ""})
+		testutil.AssertTokens(t, tt.input, expected, New(tt.input).Tokenize())
+	}
+}
+
+func TestBracketTokens(t *testing.T) {
+	input := "[1, 2, 3][0]"
+	expected := []token.Token{
+		{Type: token.LBRACKET, Literal: "["},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.COMMA, Literal: ","},
+		{Type: token.INT, Literal: "2"},
+		{Type: token.COMMA, Literal: ","},
+		{Type: token.INT, Literal: "3"},
+		{Type: token.RBRACKET, Literal: "]"},
+		{Type: token.LBRACKET, Literal: "["},
+		{Type: token.INT, Literal: "0"},
+		{Type: token.RBRACKET, Literal: "]"},
+		{Type: token.EOF, Literal: ""},
+	}
+
+	testutil.AssertTokens(t, input, expected, New(input).Tokenize())
+}
+
+func TestColonToken(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"x : y", []token.Token{
+			{Type: token.IDENT, Literal: "x", Line: 1, Column: 1},
+			{Type: token.COLON, Literal: ":", Line: 1, Column: 3},
+			{Type: token.IDENT, Literal: "y", Line: 1, Column: 5},
+		}},
+		{`{"a": 1}`, []token.Token{
+			{Type: token.LBRACE, Literal: "{"},
+			{Type: token.STRING, Literal: "a"},
+			{Type: token.COLON, Literal: ":"},
+			{Type: token.INT, Literal: "1"},
+			{Type: token.RBRACE, Literal: "}"},
+		}},
+	}
+
+	for _, tt := range tests {
+		expected := append(tt.expected, token.Token{Type: token.EOF, Literal: ""})
+		testutil.AssertTokens(t, tt.input, expected, New(tt.input).Tokenize())
+	}
+}
+
+func TestDotToken(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"a.b", []token.Token{
+			{Type: token.IDENT, Literal: "a", Line: 1, Column: 1},
+			{Type: token.DOT, Literal: ".", Line: 1, Column: 2},
+			{Type: token.IDENT, Literal: "b", Line: 1, Column: 3},
+		}},
+		// after digits the dot is part of the number
+		{"3.14", []token.Token{{Type: token.FLOAT, Literal: "3.14"}}},
+		// after an identifier it isn't, even when a digit follows
+		{"foo.5", []token.Token{
+			{Type: token.IDENT, Literal: "foo"},
+			{Type: token.DOT, Literal: "."},
+			{Type: token.INT, Literal: "5"},
+		}},
+		{"a.b.c", []token.Token{
+			{Type: token.IDENT, Literal: "a"},
+			{Type: token.DOT, Literal: "."},
+			{Type: token.IDENT, Literal: "b"},
+			{Type: token.DOT, Literal: "."},
+			{Type: token.IDENT, Literal: "c"},
+		}},
+	}
+
+	for _, tt := range tests {
+		expected := append(tt.expected, token.Token{Type: token.EOF, Literal: ""})
+		testutil.AssertTokens(t, tt.input, expected, New(tt.input).Tokenize())
+	}
+}
+
+func TestAtToken(t *testing.T) {
+	l := New("@memoize fn")
+
+	for _, expected := range []token.Token{
+		{Type: token.AT, Literal: "@", Line: 1, Column: 1},
+		{Type: token.IDENT, Literal: "memoize", Line: 1, Column: 2},
+		{Type: token.FUNCTION, Literal: "fn", Line: 1, Column: 10},
+		{Type: token.EOF, Literal: "", Line: 1, Column: 12},
+	} {
+		if tok := l.NextToken(); tok != expected {
+			t.Fatalf("token wrong. Expected = %+v, got = %+v", expected, tok)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"3", []token.Token{{Type: token.INT, Literal: "3"}}},
+		{"3.14", []token.Token{{Type: token.FLOAT, Literal: "3.14"}}},
+		{"0.0", []token.Token{{Type: token.FLOAT, Literal: "0.0"}}},
+		{"10.250 + 1", []token.Token{
+			{Type: token.FLOAT, Literal: "10.250"},
+			{Type: token.PLUS, Literal: "+"},
+			{Type: token.INT, Literal: "1"},
+		}},
+		{".", []token.Token{{Type: token.DOT, Literal: "."}}},
+		{"5.", []token.Token{{Type: token.INT, Literal: "5"}, {Type: token.DOT, Literal: "."}}},
+		{".5", []token.Token{{Type: token.DOT, Literal: "."}, {Type: token.INT, Literal: "5"}}},
+		{"0xFF", []token.Token{{Type: token.INT, Literal: "0xFF"}}},
+		{"0x1a2b", []token.Token{{Type: token.INT, Literal: "0x1a2b"}}},
+		{"0XdeadBEEF;", []token.Token{{Type: token.INT, Literal: "0XdeadBEEF"}, {Type: token.SEMICOLON, Literal: ";"}}},
+		{"0x", []token.Token{{Type: token.ILLEGAL, Literal: "0x"}}},
+		{"0xg", []token.Token{{Type: token.ILLEGAL, Literal: "0x"}, {Type: token.IDENT, Literal: "g"}}},
+		{"0", []token.Token{{Type: token.INT, Literal: "0"}}},
+		{"0b1010", []token.Token{{Type: token.INT, Literal: "0b1010"}}},
+		{"0B1", []token.Token{{Type: token.INT, Literal: "0B1"}}},
+		{"0o755", []token.Token{{Type: token.INT, Literal: "0o755"}}},
+		{"0O17)", []token.Token{{Type: token.INT, Literal: "0O17"}, {Type: token.RPAREN, Literal: ")"}}},
+		{"0b2", []token.Token{{Type: token.ILLEGAL, Literal: "0b2"}}},
+		{"0b1012", []token.Token{{Type: token.ILLEGAL, Literal: "0b1012"}}},
+		{"0o9", []token.Token{{Type: token.ILLEGAL, Literal: "0o9"}}},
+		{"0o", []token.Token{{Type: token.ILLEGAL, Literal: "0o"}}},
+		{"0b;", []token.Token{{Type: token.ILLEGAL, Literal: "0b"}, {Type: token.SEMICOLON, Literal: ";"}}},
+		{"1_000_000", []token.Token{{Type: token.INT, Literal: "1_000_000"}}},
+		{"3_141.592_653", []token.Token{{Type: token.FLOAT, Literal: "3_141.592_653"}}},
+		{"0xFF_FF", []token.Token{{Type: token.INT, Literal: "0xFF_FF"}}},
+		{"0b1010_0101", []token.Token{{Type: token.INT, Literal: "0b1010_0101"}}},
+		{"100_", []token.Token{{Type: token.ILLEGAL, Literal: "100_"}}},
+		{"1__0", []token.Token{{Type: token.ILLEGAL, Literal: "1__0"}}},
+		{"1_.5", []token.Token{{Type: token.ILLEGAL, Literal: "1_.5"}}},
+		{"0b1_2", []token.Token{{Type: token.ILLEGAL, Literal: "0b1_2"}}},
+		{"_100", []token.Token{{Type: token.IDENT, Literal: "_100"}}},
+		{"1.2.3", []token.Token{{Type: token.FLOAT, Literal: "1.2"}, {Type: token.DOT, Literal: "."}, {Type: token.INT, Literal: "3"}}},
+	}
+
+	for _, tt := range tests {
+		expected := append(tt.expected, token.Token{Type: token.EOF, Literal: ""})
+		testutil.AssertTokens(t, tt.input, expected, New(tt.input).Tokenize())
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{`""`, []token.Token{{Type: token.STRING, Literal: ""}}},
+		{`"foo"`, []token.Token{{Type: token.STRING, Literal: "foo"}}},
+		{`"foo bar"`, []token.Token{{Type: token.STRING, Literal: "foo bar"}}},
+		{`let s = "a = 1;";`, []token.Token{
+			{Type: token.LET, Literal: "let"},
+			{Type: token.IDENT, Literal: "s"},
+			{Type: token.ASSIGN, Literal: "="},
+			{Type: token.STRING, Literal: "a = 1;"},
+			{Type: token.SEMICOLON, Literal: ";"},
+		}},
+		{`"a""b"`, []token.Token{{Type: token.STRING, Literal: "a"}, {Type: token.STRING, Literal: "b"}}},
+		{"`raw`", []token.Token{{Type: token.STRING, Literal: "raw"}}},
+		{"``", []token.Token{{Type: token.STRING, Literal: ""}}},
+		{"`a\\nb`", []token.Token{{Type: token.STRING, Literal: `a\nb`}}},
+		{"`line 1\nline \"2\"\n`", []token.Token{{Type: token.STRING, Literal: "line 1\nline \"2\"\n"}}},
+		{"\"it's `fine`\"", []token.Token{{Type: token.STRING, Literal: "it's `fine`"}}},
+		{"`unterminated\n\"raw\"", []token.Token{{Type: token.ILLEGAL, Literal: "`unterminated\n\"raw\""}}},
+		{`"unterminated`, []token.Token{{Type: token.ILLEGAL, Literal: `"unterminated`}}},
+		{`x + "`, []token.Token{
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.PLUS, Literal: "+"},
+			{Type: token.ILLEGAL, Literal: `"`},
+		}},
+	}
+
+	for _, tt := range tests {
+		expected := append(tt.expected, token.Token{Type: token.EOF, Literal: ""})
+		testutil.AssertTokens(t, tt.input, expected, New(tt.input).Tokenize())
+	}
+
+	// a string spanning lines moves the position on like any other input
+	l := New("\"a\nb\" c")
+	l.NextToken()
+	if tok := l.NextToken(); tok.Line != 2 || tok.Column != 4 {
+		t.Errorf("wrong position after a multi-line string. want=2:4, got=%d:%d", tok.Line, tok.Column)
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	input := "let x = 5;\n\nlet y = \\\n  x;\nfn(a) {\n\ta\n}\n"
+
+	expected, _ := Tokenize(input)
+	for _, eol := range []string{"\r\n", "\r"} {
+		tokens, _ := Tokenize(strings.ReplaceAll(input, "\n", eol))
+
+		if len(tokens) != len(expected) {
+			t.Fatalf("wrong number of tokens with %q line endings. want=%d, got=%d", eol, len(expected), len(tokens))
+		}
+		for i := range expected {
+			if tokens[i] != expected[i] {
+				t.Errorf("tokens[%d] wrong with %q line endings. want=%+v, got=%+v", i, eol, expected[i], tokens[i])
+			}
+		}
+	}
+
+	// the last token is on line 7 however lines are ended
+	if last := expected[len(expected)-2]; last.Type != token.RBRACE || last.Line != 7 || last.Column != 1 {
+		t.Errorf("wrong closing brace. got=%+v", last)
+	}
+}
+
+func TestNewlineTokens(t *testing.T) {
+	input := "let x = 5 // five\n\nlet y = x \\\n  + 1\r\n/* a\nb */ y\r"
+
+	expected := []token.Token{
+		{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+		{Type: token.IDENT, Literal: "x", Line: 1, Column: 5},
+		{Type: token.ASSIGN, Literal: "=", Line: 1, Column: 7},
+		{Type: token.INT, Literal: "5", Line: 1, Column: 9},
+		{Type: token.NEWLINE, Literal: "\n", Line: 1, Column: 18},
+		{Type: token.NEWLINE, Literal: "\n", Line: 2, Column: 1},
+		{Type: token.LET, Literal: "let", Line: 3, Column: 1},
+		{Type: token.IDENT, Literal: "y", Line: 3, Column: 5},
+		{Type: token.ASSIGN, Literal: "=", Line: 3, Column: 7},
+		{Type: token.IDENT, Literal: "x", Line: 3, Column: 9},
+		{Type: token.PLUS, Literal: "+", Line: 4, Column: 3},
+		{Type: token.INT, Literal: "1", Line: 4, Column: 5},
+		{Type: token.NEWLINE, Literal: "\r\n", Line: 4, Column: 6},
+		{Type: token.IDENT, Literal: "y", Line: 6, Column: 6},
+		{Type: token.NEWLINE, Literal: "\r", Line: 6, Column: 7},
+		{Type: token.EOF, Literal: "", Line: 7, Column: 1},
+	}
+	testutil.AssertTokens(t, input, expected, New(input, WithNewlineTokens()).Tokenize())
+
+	// without the option the same source lexes to the same tokens minus the newlines
+	var withoutNewlines []token.Token
+	for _, tok := range expected {
+		if tok.Type != token.NEWLINE {
+			withoutNewlines = append(withoutNewlines, tok)
+		}
+	}
+	testutil.AssertTokens(t, input, withoutNewlines, New(input).Tokenize())
+}
+
+func TestComments(t *testing.T) {
+	letX := []token.Token{
+		{Type: token.LET, Literal: "let"},
+		{Type: token.IDENT, Literal: "x"},
+		{Type: token.ASSIGN, Literal: "="},
+		{Type: token.INT, Literal: "5"},
+		{Type: token.SEMICOLON, Literal: ";"},
+	}
+
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"let x = 5; // comment", letX},
+		{"let x = 5; // comment\n", letX},
+		{"// first\n// second\r\nlet x = 5;//", letX},
+		{"let x = // the value\n 5;", letX},
+		{"//", nil},
+		{"10 / 2 // halved", []token.Token{
+			{Type: token.INT, Literal: "10"},
+			{Type: token.SLASH, Literal: "/"},
+			{Type: token.INT, Literal: "2"},
+		}},
+		{"a // b\nc", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.IDENT, Literal: "c"}}},
+	}
+
+	for _, tt := range tests {
+		expected := append(tt.expected, token.Token{Type: token.EOF, Literal: ""})
+		testutil.AssertTokens(t, tt.input, expected, New(tt.input).Tokenize())
+	}
+
+	l := New("// comment\n  x")
+	if tok := l.NextToken(); tok.Line != 2 || tok.Column != 3 {
+		t.Errorf("wrong position after a comment. want=2:3, got=%d:%d", tok.Line, tok.Column)
+	}
+}
+
+func TestBlockComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"/* comment */", nil},
+		{"a /* b */ c", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.IDENT, Literal: "c"}}},
+		{"a/**/c", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.IDENT, Literal: "c"}}},
+		{"a /* /* b */ c", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.IDENT, Literal: "c"}}},
+		{"a /* b ** / */ c", []token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.IDENT, Literal: "c"}}},
+		{"10 /*/ 2", []token.Token{{Type: token.INT, Literal: "10"}, {Type: token.ILLEGAL, Literal: "/*/ 2"}}},
+		{"a /* never closed\n b", []token.Token{
+			{Type: token.IDENT, Literal: "a"},
+			{Type: token.ILLEGAL, Literal: "/* never closed\n b"},
+		}},
+		{"a /* b */ c /* d", []token.Token{
+			{Type: token.IDENT, Literal: "a"},
+			{Type: token.IDENT, Literal: "c"},
+			{Type: token.ILLEGAL, Literal: "/* d"},
+		}},
+	}
+
+	for _, tt := range tests {
+		expected := append(tt.expected, token.Token{Type: token.EOF, Literal: ""})
+		testutil.AssertTokens(t, tt.input, expected, New(tt.input).Tokenize())
+	}
+
+	input := `let x = 1; /* a comment
+spanning
+three lines */ let y = 2;
+z`
+	l := New(input)
+	for _, expected := range []token.Token{
+		{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+		{Type: token.IDENT, Literal: "x", Line: 1, Column: 5},
+		{Type: token.ASSIGN, Literal: "=", Line: 1, Column: 7},
+		{Type: token.INT, Literal: "1", Line: 1, Column: 9},
+		{Type: token.SEMICOLON, Literal: ";", Line: 1, Column: 10},
+		{Type: token.LET, Literal: "let", Line: 3, Column: 16},
+		{Type: token.IDENT, Literal: "y", Line: 3, Column: 20},
+		{Type: token.ASSIGN, Literal: "=", Line: 3, Column: 22},
+		{Type: token.INT, Literal: "2", Line: 3, Column: 24},
+		{Type: token.SEMICOLON, Literal: ";", Line: 3, Column: 25},
+		{Type: token.IDENT, Literal: "z", Line: 4, Column: 1},
+	} {
+		if tok := l.NextToken(); tok != expected {
+			t.Fatalf("token wrong. Expected = %+v, got = %+v", expected, tok)
+		}
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	input := "let café = λ + 変数;\nΣ_x €\xff x"
+
+	l := New(input)
+	for _, expected := range []token.Token{
+		{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+		{Type: token.IDENT, Literal: "café", Line: 1, Column: 5},
+		{Type: token.ASSIGN, Literal: "=", Line: 1, Column: 10},
+		{Type: token.IDENT, Literal: "λ", Line: 1, Column: 12},
+		{Type: token.PLUS, Literal: "+", Line: 1, Column: 14},
+		{Type: token.IDENT, Literal: "変数", Line: 1, Column: 16},
+		{Type: token.SEMICOLON, Literal: ";", Line: 1, Column: 18},
+		{Type: token.IDENT, Literal: "Σ_x", Line: 2, Column: 1},
+		{Type: token.ILLEGAL, Literal: "€", Line: 2, Column: 5},
+		{Type: token.ILLEGAL, Literal: "\xff", Line: 2, Column: 6},
+		{Type: token.IDENT, Literal: "x", Line: 2, Column: 8},
+		{Type: token.EOF, Literal: "", Line: 2, Column: 9},
+	} {
+		if tok := l.NextToken(); tok != expected {
+			t.Fatalf("token wrong. Expected = %+v, got = %+v", expected, tok)
+		}
+	}
+
+	// digits from other scripts aren't numbers
+	l = New("١٢")
+	if tok := l.NextToken(); tok.Type != token.ILLEGAL || tok.Literal != "١" {
+		t.Errorf("Arabic-Indic digit lexed wrong. got=%+v", tok)
+	}
+}
+
+func TestIdentifiersWithDigits(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{"foo1", []token.Token{{Type: token.IDENT, Literal: "foo1"}}},
+		{"a2b3", []token.Token{{Type: token.IDENT, Literal: "a2b3"}}},
+		{"a_1 value2", []token.Token{{Type: token.IDENT, Literal: "a_1"}, {Type: token.IDENT, Literal: "value2"}}},
+		{"x1+y2", []token.Token{
+			{Type: token.IDENT, Literal: "x1"},
+			{Type: token.PLUS, Literal: "+"},
+			{Type: token.IDENT, Literal: "y2"},
+		}},
+		{"1x", []token.Token{{Type: token.INT, Literal: "1"}, {Type: token.IDENT, Literal: "x"}}},
+		{"let1", []token.Token{{Type: token.IDENT, Literal: "let1"}}},
+		{"fn2()", []token.Token{
+			{Type: token.IDENT, Literal: "fn2"},
+			{Type: token.LPAREN, Literal: "("},
+			{Type: token.RPAREN, Literal: ")"},
+		}},
+	}
+
+	for _, tt := range tests {
+		expected := append(tt.expected, token.Token{Type: token.EOF, Literal: ""})
+		testutil.AssertTokens(t, tt.input, expected, New(tt.input).Tokenize())
+	}
+}
+
+func TestLineContinuation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{
+			"a + \\\nb",
+			[]token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.PLUS, Literal: "+"}, {Type: token.IDENT, Literal: "b"}},
+		},
+		{
+			"a \\\r\n  + b",
+			[]token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.PLUS, Literal: "+"}, {Type: token.IDENT, Literal: "b"}},
+		},
+		{
+			"a\\\n\\\nb",
+			[]token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.IDENT, Literal: "b"}},
+		},
+		{
+			"a \\ b",
+			[]token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.ILLEGAL, Literal: "\\"}, {Type: token.IDENT, Literal: "b"}},
+		},
+		{
+			"a \\",
+			[]token.Token{{Type: token.IDENT, Literal: "a"}, {Type: token.ILLEGAL, Literal: "\\"}},
+		},
+	}
+
+	for _, tt := range tests {
+		expected := append(tt.expected, token.Token{Type: token.EOF, Literal: ""})
+		testutil.AssertTokens(t, tt.input, expected, New(tt.input).Tokenize())
+	}
+
+	// the continued line still counts as a line
+	l := New("a + \\\n  b\nc")
+	for _, expected := range []token.Token{
+		{Type: token.IDENT, Literal: "a", Line: 1, Column: 1},
+		{Type: token.PLUS, Literal: "+", Line: 1, Column: 3},
+		{Type: token.IDENT, Literal: "b", Line: 2, Column: 3},
+		{Type: token.IDENT, Literal: "c", Line: 3, Column: 1},
+	} {
+		if tok := l.NextToken(); tok != expected {
+			t.Fatalf("token wrong. Expected = %+v, got = %+v", expected, tok)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	input := `let five = 5;
+  five == 10;
+
+fn(x) {
+	x
+}`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1, 1},
+		{token.IDENT, "five", 1, 5},
+		{token.ASSIGN, "=", 1, 10},
+		{token.INT, "5", 1, 12},
+		{token.SEMICOLON, ";", 1, 13},
+		{token.IDENT, "five", 2, 3},
+		{token.EQ, "==", 2, 8},
+		{token.INT, "10", 2, 11},
+		{token.SEMICOLON, ";", 2, 13},
+		{token.FUNCTION, "fn", 4, 1},
+		{token.LPAREN, "(", 4, 3},
+		{token.IDENT, "x", 4, 4},
+		{token.RPAREN, ")", 4, 5},
+		{token.LBRACE, "{", 4, 7},
+		{token.IDENT, "x", 5, 2},
+		{token.RBRACE, "}", 6, 1},
+		{token.EOF, "", 6, 2},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - wrong position for %q. expected=%d:%d, got=%d:%d",
+				i, tok.Literal, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestNewFromReader(t *testing.T) {
+	inputs := []string{
+		"let add = fn(x, y) {\n\tx + y; // sum\n};\nadd(1, 2) == 3;",
+		"let café = \"λ → ∀\"; /* a\r\ncomment */ café <= 0x_1F\r\n",
+		"let s = `raw\nstring`; 1.5 /* not closed",
+		"\"not closed",
+	}
+
+	// a reader handing out one byte at a time splits every token and every multibyte character
+	for _, input := range inputs {
+		for _, r := range []io.Reader{strings.NewReader(input), iotest.OneByteReader(strings.NewReader(input))} {
+			l, err := NewFromReader(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			expected := New(input)
+			for {
+				want, got := expected.NextToken(), l.NextToken()
+				if got != want {
+					t.Fatalf("token wrong for %q. Expected = %+v, got = %+v", input, want, got)
+				}
+				if l.Position() != expected.Position() {
+					t.Fatalf("Position() wrong for %q. Expected = %d, got = %d", input, expected.Position(),
+						l.Position())
+				}
+				if got.Type == token.EOF {
+					break
+				}
+			}
+		}
+	}
+
+	l, err := NewFromReader(strings.NewReader("LET x"), WithCaseInsensitiveKeywords())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if tok := l.NextToken(); tok.Type != token.LET {
+		t.Errorf("options not applied. got=%+v", tok)
+	}
+
+	if _, err := NewFromReader(iotest.ErrReader(errors.New("boom"))); err == nil || err.Error() != "boom" {
+		t.Errorf("read error not returned. got=%v", err)
+	}
+
+	// an error after the first read ends the input
+	l, err = NewFromReader(io.MultiReader(strings.NewReader("let x"), iotest.ErrReader(errors.New("boom"))))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	testutil.AssertTokens(t, "let x", New("let x").Tokenize(), l.Tokenize())
+	if l.Err() == nil || l.Err().Error() != "boom" {
+		t.Errorf("read error not reported. got=%v", l.Err())
+	}
+}
+
+func TestNewFromReaderDoesNotBuffer(t *testing.T) {
+	const statements = 100000
+	line := "let x = x + 1; // count\n"
+
+	l, err := NewFromReader(strings.NewReader(strings.Repeat(line, statements)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	count := 0
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		count++
+		if len(l.input) > 2*readSize {
+			t.Fatalf("lexer holds %d bytes of its input after %d tokens", len(l.input), count)
+		}
+	}
+	if count != 7*statements {
+		t.Errorf("wrong number of tokens. want=%d, got=%d", 7*statements, count)
+	}
+	if l.Err() != nil {
+		t.Errorf("unexpected error: %s", l.Err())
+	}
+}
+
+func TestNewFromReaderMarkAndClone(t *testing.T) {
+	input := strings.Repeat("let x = 5;\n", 2000)
+	expected := New(input).Tokenize()
+
+	l, err := NewFromReader(iotest.HalfReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i := 0; i < 10; i++ {
+		l.NextToken()
+	}
+
+	// the input after a mark is kept, however far the lexer gets past it
+	mark := l.Mark()
+	l.Tokenize()
+	l.Reset(mark)
+	if rest := l.Tokenize(); len(rest) != len(expected)-10 || rest[0] != expected[10] {
+		t.Errorf("Reset() doesn't return to the mark. got %d tokens, first=%+v", len(rest), rest[0])
+	}
+
+	// a clone doesn't share the reader with the lexer it's cloned from
+	l, err = NewFromReader(iotest.HalfReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	l.NextToken()
+	clone := l.Clone()
+	testutil.AssertTokens(t, input, expected[1:], clone.Tokenize())
+	testutil.AssertTokens(t, input, expected[1:], l.Tokenize())
+}
+
+func TestLexerTokenize(t *testing.T) {
+	input := "let x = 5; x + $;"
+
+	tokens := New(input).Tokenize()
+	if len(tokens) != 10 {
+		t.Fatalf("wrong number of tokens. want=10, got=%d (%+v)", len(tokens), tokens)
+	}
+	if last := tokens[len(tokens)-1]; last.Type != token.EOF {
+		t.Errorf("last token is not EOF. got=%+v", last)
+	}
+
+	again := New(input).Tokenize()
+	for i := range tokens {
+		if tokens[i] != again[i] {
+			t.Errorf("tokens[%d] differs between runs. first=%+v, second=%+v", i, tokens[i], again[i])
+		}
+	}
+
+	l := New(input)
+	l.NextToken()
+	l.NextToken()
+	if rest := l.Tokenize(); len(rest) != 8 || rest[0].Type != token.ASSIGN {
+		t.Errorf("Tokenize() doesn't continue from the current position. got=%+v", rest)
+	}
+	if rest := l.Tokenize(); len(rest) != 1 || rest[0].Type != token.EOF {
+		t.Errorf("Tokenize() at the end of the input should return just EOF. got=%+v", rest)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	input := "let add = fn(x, y) { x + y; }; add(1, 2) == 3;"
+
+	tokens, errs := Tokenize(input)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	l := New(input)
+	for i, tok := range tokens {
+		expected := l.NextToken()
+		if tok != expected {
+			t.Fatalf("tokens[%d] wrong. Expected = %+v, got = %+v", i, expected, tok)
+		}
+	}
+
+	if last := tokens[len(tokens)-1]; last.Type != token.EOF {
+		t.Fatalf("last token is not EOF. got = %q", last.Type)
+	}
+}
+
+func TestTokenizeIllegal(t *testing.T) {
+	tokens, errs := Tokenize("let x = 5 ? 3 $;\nlet y = 0x + \"abc")
+
+	expected := []string{
+		`illegal token "?" at 1:11`,
+		`illegal token "$" at 1:15`,
+		`illegal token "0x" at 2:9`,
+		`illegal token "\"abc" at 2:14`,
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("wrong number of errors. Expected = %d, got = %d (%v)", len(expected), len(errs), errs)
+	}
+
+	for i, msg := range expected {
+		if errs[i].Error() != msg {
+			t.Errorf("errs[%d] wrong. Expected = %q, got = %q", i, msg, errs[i].Error())
+		}
+	}
+
+	// lexing doesn't stop at the first illegal character
+	if last := tokens[len(tokens)-1]; last.Type != token.EOF {
+		t.Fatalf("last token is not EOF. got = %q", last.Type)
+	}
+}
+
+func TestCaseInsensitiveKeywords(t *testing.T) {
+	input := "Let x = FN(y) { RETURN y; };"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "Let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.FUNCTION, "FN"},
+		{token.LPAREN, "("},
+		{token.IDENT, "y"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RETURN, "RETURN"},
+		{token.IDENT, "y"},
+	}
+
+	l := New(input, WithCaseInsensitiveKeywords())
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. Expected = %q, got = %q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. Expected = %q, got = %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	// keywords stay case-sensitive by default
+	if tok := New(input).NextToken(); tok.Type != token.IDENT {
+		t.Fatalf("default lexer treats %q as a keyword. got = %q", tok.Literal, tok.Type)
+	}
+}
+
+func TestPosition(t *testing.T) {
+	input := "let x = 10;\n  x == 10"
+
+	tests := []struct {
+		expectedLiteral  string
+		expectedPosition int
+	}{
+		{"let", 3},
+		{"x", 5},
+		{"=", 7},
+		{"10", 10},
+		{";", 11},
+		{"x", 15},
+		{"==", 18},
+		{"10", 21},
+		{"", 21},
+		{"", 21}, // reading past EOF doesn't move the position
+	}
+
+	l := New(input)
+	if l.Position() != 0 {
+		t.Fatalf("initial position wrong. Expected = 0, got = %d", l.Position())
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. Expected = %q, got = %q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		pos := l.Position()
+		if pos != tt.expectedPosition {
+			t.Fatalf("tests[%d] - position wrong. Expected = %d, got = %d", i, tt.expectedPosition, pos)
+		}
+
+		// the token ends right where the position points to
+		if !strings.HasSuffix(input[:pos], tok.Literal) {
+			t.Fatalf("tests[%d] - input[:%d] = %q doesn't end with %q", i, pos, input[:pos], tok.Literal)
+		}
+	}
+}
+
+func TestUnread(t *testing.T) {
+	l := New("let x = 5;")
+
+	let := l.NextToken()
+	if err := l.Unread(let); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := l.Unread(let); err != ErrUnreadFull {
+		t.Errorf("second Unread() wrong error. want=%v, got=%v", ErrUnreadFull, err)
+	}
+
+	expected := []token.Token{
+		{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+		{Type: token.IDENT, Literal: "x", Line: 1, Column: 5},
+		{Type: token.ASSIGN, Literal: "=", Line: 1, Column: 7},
+		{Type: token.INT, Literal: "5", Line: 1, Column: 9},
+		{Type: token.SEMICOLON, Literal: ";", Line: 1, Column: 10},
+		{Type: token.EOF, Literal: "", Line: 1, Column: 11},
+	}
+	testutil.AssertTokens(t, "let x = 5;", expected, l.Tokenize())
+
+	// the slot is free again once the token has been read, and any token can be pushed back, even at EOF
+	semicolon := token.Token{Type: token.SEMICOLON, Literal: ";"}
+	if err := l.Unread(semicolon); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if tok := l.NextToken(); tok != semicolon {
+		t.Errorf("wrong token after Unread() at EOF. want=%+v, got=%+v", semicolon, tok)
+	}
+	if tok := l.NextToken(); tok.Type != token.EOF {
+		t.Errorf("scanning didn't resume after the unread token. got=%+v", tok)
+	}
+}
+
+func TestPeekToken(t *testing.T) {
+	input := "let add = fn(x, y) {\n  x + y;\n};\nadd(1, 2);"
+	expected := New(input).Tokenize()
+
+	// peek zero, one or two times before every NextToken(), in a pattern that doesn't line up with the tokens
+	l := New(input)
+	var tokens []token.Token
+	for i := 0; ; i++ {
+		for j := 0; j < i%3; j++ {
+			if peeked := l.PeekToken(); peeked != expected[len(tokens)] {
+				t.Fatalf("PeekToken() before tokens[%d] wrong. want=%+v, got=%+v", len(tokens), expected[len(tokens)], peeked)
+			}
+		}
+		tokens = append(tokens, l.NextToken())
+		if tokens[len(tokens)-1].Type == token.EOF {
+			break
+		}
+	}
+	testutil.AssertTokens(t, input, expected, tokens)
+
+	// an unread token comes before the peeked one, and peeking doesn't move Position()
+	l = New("a b c")
+	a := l.NextToken()
+	position := l.Position()
+	if peeked := l.PeekToken(); peeked.Literal != "b" {
+		t.Fatalf("PeekToken() wrong. want=%q, got=%+v", "b", peeked)
+	}
+	if l.Position() != position {
+		t.Errorf("PeekToken() moved Position(). want=%d, got=%d", position, l.Position())
+	}
+	if err := l.Unread(a); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if peeked := l.PeekToken(); peeked != a {
+		t.Errorf("PeekToken() after Unread() wrong. want=%+v, got=%+v", a, peeked)
+	}
+	for _, literal := range []string{"a", "b", "c", ""} {
+		if tok := l.NextToken(); tok.Literal != literal {
+			t.Errorf("NextToken() wrong. want=%q, got=%+v", literal, tok)
+		}
+	}
+}
+
+func TestMarkAndReset(t *testing.T) {
+	input := "let x = 5;\nlet y = x * 2;"
+	expected := New(input).Tokenize()
+
+	l := New(input)
+	var tokens []token.Token
+	for i := 0; i < 4; i++ { // let x = 5
+		tokens = append(tokens, l.NextToken())
+	}
+
+	mark := l.Mark()
+	var ahead []token.Token
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		ahead = append(ahead, tok)
+	}
+
+	l.Reset(mark)
+	var replay []token.Token
+	for i := range ahead {
+		replay = append(replay, l.NextToken())
+		if replay[i] != ahead[i] {
+			t.Fatalf("replayed token %d differs. want=%+v, got=%+v", i, ahead[i], replay[i])
+		}
+	}
+	testutil.AssertTokens(t, input, expected, append(append(tokens, replay...), l.NextToken()))
+
+	// unread and peeked tokens are part of the state
+	l = New("a b c")
+	a := l.NextToken()
+	if err := l.Unread(a); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	mark = l.Mark()
+	l.NextToken()
+	l.PeekToken()
+	l.Reset(mark)
+	for _, literal := range []string{"a", "b", "c", ""} {
+		if tok := l.NextToken(); tok.Literal != literal {
+			t.Errorf("NextToken() after Reset() wrong. want=%q, got=%+v", literal, tok)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	l := New("let five = 5;")
+
+	l.NextToken() // let
+	l.NextToken() // five
+
+	clone := l.Clone()
+
+	for _, expected := range []token.TokenType{token.ASSIGN, token.INT, token.SEMICOLON, token.EOF} {
+		if tok := clone.NextToken(); tok.Type != expected {
+			t.Fatalf("clone token wrong. Expected = %q, got = %q", expected, tok.Type)
+		}
+	}
+
+	tok := l.NextToken()
+	if tok.Type != token.ASSIGN || tok.Literal != "=" {
+		t.Fatalf("original lexer was affected by clone. Expected = %q, got = %q (%q)", token.ASSIGN, tok.Type,
+			tok.Literal)
+	}
+}
+
+func TestNewTokenLiteral(t *testing.T) {
+	for _, ch := range []rune{0, 'a', '=', 0x7f, 0xe9, 0xff, 0x100, 'λ', '世', '😀'} {
+		if tok := newToken(token.ILLEGAL, ch); tok.Literal != string(ch) {
+			t.Fatalf("newToken literal for %U wrong. Expected = %q, got = %q", ch, string(ch), tok.Literal)
+		}
+	}
+	for i := 0; i < 256; i++ {
+		if tok := newToken(token.ILLEGAL, rune(i)); tok.Literal != string(rune(i)) {
+			t.Fatalf("newToken literal for %U wrong. Expected = %q, got = %q", i, string(rune(i)), tok.Literal)
+		}
+	}
+}
+
+func TestSingleCharTokens(t *testing.T) {
+	for ch := 0; ch < len(singleCharTokens); ch++ {
+		tok := singleCharTokens[ch]
+		if tok.Type == "" {
+			continue
+		}
+
+		// a cached token must be exactly what the lexer built for the character before the table existed
+		if tok != newToken(tok.Type, rune(ch)) {
+			t.Errorf("singleCharTokens[%q] wrong. got=%+v", ch, tok)
+		}
+
+		input := "x" + string(rune(ch)) + "y"
+		expected := []token.Token{
+			{Type: token.IDENT, Literal: "x", Line: 1, Column: 1},
+			{Type: tok.Type, Literal: string(rune(ch)), Line: 1, Column: 2},
+			{Type: token.IDENT, Literal: "y", Line: 1, Column: 3},
+			{Type: token.EOF, Literal: "", Line: 1, Column: 4},
+		}
+		testutil.AssertTokens(t, input, expected, New(input).Tokenize())
+	}
+}
+
+func TestNextTokenDoesNotAllocate(t *testing.T) {
+	input := "let x = !(a + b) * c / d == e != f < g > h;"
+
+	allocs := testing.AllocsPerRun(100, func() {
+		l := New(input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+	})
+
+	// the only allocation left is the Lexer itself
+	if allocs > 1 {
+		t.Errorf("tokenizing allocated too much. Expected <= 1 allocation, got = %v", allocs)
+	}
+}
+
+func BenchmarkNextToken(b *testing.B) {
+	source := strings.Repeat(`let add = fn(x, y) {
+	if (x < y) { return x + y; } else { return !(x - y) * 2 / 1; }
+};
+let result = add(five, ten) == 15 != false;
+`, 200)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l := New(source)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+	}
+}
+
+func BenchmarkNextTokenOperators(b *testing.B) {
+	source := strings.Repeat("(a + b) * -c % d, f(g) < h > i; { @j }\n", 500)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l := New(source)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+	}
+}
+
+func FuzzTokenize(f *testing.F) {
+	for _, seed := range []string{"", "let x = 5;", "fn(a, b) { a != b }", "@dec $?", "LET çay = 1", "\x00\xff"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		tokens, errors := Tokenize(input)
+
+		if len(tokens) == 0 || tokens[len(tokens)-1].Type != token.EOF {
+			t.Fatalf("token stream for %q doesn't end in EOF: %v", input, tokens)
+		}
+
+		illegal := 0
+		for _, tok := range tokens {
+			if tok.Type == token.ILLEGAL {
+				illegal++
+			}
+		}
+		if illegal != len(errors) {
+			t.Errorf("got %d ILLEGAL tokens but %d errors for %q", illegal, len(errors), input)
+		}
+	})
+}
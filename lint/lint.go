@@ -0,0 +1,158 @@
+package lint
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+)
+
+// Severity says how bad a Diagnostic is. Errors stop a program from running at all; warnings point at code that runs
+// but is probably not what was meant.
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic is a single problem found by the parser or a lint check, together with the node it was found at and the
+// position of that node's first token. Parser errors have no node, so Node is nil and the position is that of the token
+// the parser gave up at.
+
+type Diagnostic struct {
+	Severity Severity
+	Message  string
+	Node     ast.Node
+	Line     int
+	Column   int
+}
+
+// String() renders the diagnostic the way compilers do, e.g. "3:5: warning: unreachable code after return: x". The
+// position is left out when there is none.
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return d.Severity.String() + ": " + d.Message
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Severity, d.Message)
+}
+
+// Diagnostics is a list of diagnostics of mixed severity.
+
+type Diagnostics []Diagnostic
+
+// Count() returns how many of the diagnostics have the given severity.
+
+func (ds Diagnostics) Count(severity Severity) int {
+	n := 0
+	for _, d := range ds {
+		if d.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// HasErrors() reports whether any of the diagnostics is an error.
+
+func (ds Diagnostics) HasErrors() bool {
+	return ds.Count(SeverityError) > 0
+}
+
+// Check() parses input and runs every lint check over it. Parser errors are reported as errors and come first; lint
+// findings are warnings. The checks still run when there are parser errors, over whatever the parser could recover.
+
+func Check(input string) Diagnostics {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	diagnostics := Diagnostics{}
+	tokens := p.ErrorTokens()
+	for i, msg := range p.Errors() {
+		diagnostics = append(diagnostics, Diagnostic{Severity: SeverityError, Message: msg, Line: tokens[i].Line,
+			Column: tokens[i].Column})
+	}
+	diagnostics = append(diagnostics, UnreachableAfterReturn(program)...)
+
+	return diagnostics
+}
+
+// UnreachableAfterReturn() flags every statement that follows a return statement in the same block (or at the top
+// level of the program). Only returns directly in a block count: a return inside a nested block, like the consequence
+// of an if, may not be taken, so it doesn't make the statements after the if unreachable.
+
+func UnreachableAfterReturn(program *ast.Program) Diagnostics {
+	diagnostics := Diagnostics{}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Program:
+			diagnostics = append(diagnostics, unreachableIn(node.Statements)...)
+		case *ast.BlockStatement:
+			diagnostics = append(diagnostics, unreachableIn(node.Statements)...)
+		}
+		return true
+	})
+
+	return diagnostics
+}
+
+func unreachableIn(statements []ast.Statement) []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	for i, s := range statements {
+		if _, ok := s.(*ast.ReturnStatement); !ok {
+			continue
+		}
+
+		for _, dead := range statements[i+1:] {
+			tok := statementToken(dead)
+			diagnostics = append(diagnostics, Diagnostic{
+				Severity: SeverityWarning,
+				Message:  "unreachable code after return: " + dead.String(),
+				Node:     dead,
+				Line:     tok.Line,
+				Column:   tok.Column,
+			})
+		}
+		break
+	}
+
+	return diagnostics
+}
+
+// statementToken() returns the token a statement starts with. For an expression statement that's the first token of
+// the expression.
+
+func statementToken(s ast.Statement) token.Token {
+	switch s := s.(type) {
+	case *ast.LetStatement:
+		return s.Token
+	case *ast.MultiLetStatement:
+		return s.Token
+	case *ast.MultiAssignStatement:
+		return s.Token
+	case *ast.ReturnStatement:
+		return s.Token
+	case *ast.ExpressionStatement:
+		return s.Token
+	case *ast.BlockStatement:
+		return s.Token
+	}
+	return token.Token{}
+}
@@ -0,0 +1,120 @@
+package lint
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func TestUnreachableAfterReturn(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			"return 1; let x = 2; x;",
+			[]string{
+				"unreachable code after return: let x = 2;",
+				"unreachable code after return: x",
+			},
+		},
+		{
+			"let f = fn() { return 1; 2; };",
+			[]string{"unreachable code after return: 2"},
+		},
+		{
+			// the return is inside the if's block, so the statement after the if is reachable
+			"let f = fn(x) { if (x) { return 1; } 2; };",
+			[]string{},
+		},
+		{
+			"let f = fn(x) { if (x) { return 1; 3; } else { return 2; } 4; };",
+			[]string{"unreachable code after return: 3"},
+		},
+		{
+			"let x = 1; return x;",
+			[]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		diagnostics := UnreachableAfterReturn(parse(t, tt.input))
+
+		if len(diagnostics) != len(tt.expected) {
+			t.Errorf("wrong number of diagnostics for %q. want=%d, got=%d (%+v)",
+				tt.input, len(tt.expected), len(diagnostics), diagnostics)
+			continue
+		}
+
+		for i, msg := range tt.expected {
+			if diagnostics[i].Message != msg {
+				t.Errorf("diagnostics[%d] wrong for %q. want=%q, got=%q", i, tt.input, msg, diagnostics[i].Message)
+			}
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		errors   int
+		warnings int
+		expected []string
+	}{
+		{
+			"let x 5; return 1; 2;",
+			1, 1,
+			[]string{
+				"1:7: error: expected next token to be =, got INT instead",
+				"1:20: warning: unreachable code after return: 2",
+			},
+		},
+		{"let x = 1; return x;", 0, 0, []string{}},
+		{"return 1; 2; 3;", 0, 2, []string{
+			"1:11: warning: unreachable code after return: 2",
+			"1:14: warning: unreachable code after return: 3",
+		}},
+		{"let f = fn() {\n  return 1;\n  let x = 2;\n};", 0, 1, []string{
+			"3:3: warning: unreachable code after return: let x = 2;",
+		}},
+		{"return 1; let a, b = 1, ;", 1, 0, []string{"1:25: error: no prefix parse function for ; found"}},
+	}
+
+	for _, tt := range tests {
+		diagnostics := Check(tt.input)
+
+		if diagnostics.Count(SeverityError) != tt.errors {
+			t.Errorf("wrong number of errors for %q. want=%d, got=%d", tt.input, tt.errors,
+				diagnostics.Count(SeverityError))
+		}
+		if diagnostics.Count(SeverityWarning) != tt.warnings {
+			t.Errorf("wrong number of warnings for %q. want=%d, got=%d", tt.input, tt.warnings,
+				diagnostics.Count(SeverityWarning))
+		}
+		if diagnostics.HasErrors() != (tt.errors > 0) {
+			t.Errorf("HasErrors() wrong for %q. got=%t", tt.input, diagnostics.HasErrors())
+		}
+
+		if len(diagnostics) != len(tt.expected) {
+			t.Errorf("wrong number of diagnostics for %q. want=%d, got=%d (%v)",
+				tt.input, len(tt.expected), len(diagnostics), diagnostics)
+			continue
+		}
+		for i, msg := range tt.expected {
+			if diagnostics[i].String() != msg {
+				t.Errorf("diagnostics[%d] wrong for %q. want=%q, got=%q", i, tt.input, msg, diagnostics[i].String())
+			}
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
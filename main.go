@@ -1,13 +1,25 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
+	"monkey/lexer"
 	"monkey/repl"
+	"monkey/token"
 	"os"
 	"os/user"
 )
 
+var tokensJSON = flag.Bool("tokens-json", false, "read a program from stdin and print its tokens as JSON")
+
 func main() {
+	flag.Parse()
+
+	if *tokensJSON {
+		os.Exit(printTokensJSON(os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -17,3 +29,31 @@ func main() {
 	fmt.Printf("Feel free to type in commands\n")
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+// printTokensJSON() tokenizes everything read from in and writes the token stream to out as JSON. Lexical errors are
+// reported on errOut; the return value is the process exit code.
+
+func printTokensJSON(in io.Reader, out, errOut io.Writer) int {
+	input, err := io.ReadAll(in)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+
+	tokens, errs := lexer.Tokenize(string(input))
+
+	encoded, err := token.ToJSON(tokens)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+	fmt.Fprintln(out, string(encoded))
+
+	for _, err := range errs {
+		fmt.Fprintln(errOut, err)
+	}
+	if len(errs) != 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,52 @@
+package object
+
+import "fmt"
+
+// FromGo() converts a Go value into the corresponding Monkey object for host programs that embed the interpreter:
+// Go integers become INTEGER, bool becomes BOOLEAN and nil becomes NULL. Values that already are Monkey objects are
+// returned unchanged. Monkey has no floats, strings, arrays or hashes yet, so there's nothing to convert the other Go
+// types to; they're rejected with an error.
+
+func FromGo(v interface{}) (Object, error) {
+	switch v := v.(type) {
+	case nil:
+		return NULL, nil
+	case Object:
+		return v, nil
+	case bool:
+		if v {
+			return TRUE, nil
+		}
+		return FALSE, nil
+	case int:
+		return &Integer{Value: int64(v)}, nil
+	case int8:
+		return &Integer{Value: int64(v)}, nil
+	case int16:
+		return &Integer{Value: int64(v)}, nil
+	case int32:
+		return &Integer{Value: int64(v)}, nil
+	case int64:
+		return &Integer{Value: v}, nil
+	default:
+		return nil, fmt.Errorf("cannot convert %T to a Monkey object", v)
+	}
+}
+
+// ToGo() is the reverse of FromGo(): INTEGER becomes int64, BOOLEAN becomes bool and NULL becomes nil.
+
+func ToGo(obj Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return obj.Value, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *Null:
+		return nil, nil
+	default:
+		if obj == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot convert %s to a Go value", obj.Type())
+	}
+}
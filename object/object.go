@@ -2,8 +2,11 @@ package object
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"monkey/ast"
+	"sort"
 	"strings"
 )
 
@@ -16,6 +19,7 @@ const (
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
+	BUILTIN_OBJ      = "BUILTIN"
 )
 
 type Object interface {
@@ -30,6 +34,55 @@ type Integer struct {
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 
+// The arithmetic methods below centralize Monkey's numeric semantics so the evaluator doesn't have to repeat them.
+// Integers are 64-bit and wrap around on overflow, exactly like Go's int64. Division truncates towards zero and
+// dividing by zero is an error instead of a Go runtime panic.
+
+var ErrDivisionByZero = errors.New("division by zero")
+
+func (i *Integer) Add(other Object) (Object, error) {
+	o, err := i.operand("+", other)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: i.Value + o.Value}, nil
+}
+
+func (i *Integer) Sub(other Object) (Object, error) {
+	o, err := i.operand("-", other)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: i.Value - o.Value}, nil
+}
+
+func (i *Integer) Mul(other Object) (Object, error) {
+	o, err := i.operand("*", other)
+	if err != nil {
+		return nil, err
+	}
+	return &Integer{Value: i.Value * o.Value}, nil
+}
+
+func (i *Integer) Div(other Object) (Object, error) {
+	o, err := i.operand("/", other)
+	if err != nil {
+		return nil, err
+	}
+	if o.Value == 0 {
+		return nil, ErrDivisionByZero
+	}
+	return &Integer{Value: i.Value / o.Value}, nil
+}
+
+func (i *Integer) operand(operator string, other Object) (*Integer, error) {
+	o, ok := other.(*Integer)
+	if !ok {
+		return nil, fmt.Errorf("type mismatch: %s %s %s", i.Type(), operator, other.Type())
+	}
+	return o, nil
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -37,6 +90,41 @@ type Boolean struct {
 func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
 func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
 
+// NULL, TRUE and FALSE are the only instances of their values. Code producing null or a boolean must use them
+// instead of allocating a new object, since the evaluator compares these values by pointer (e.g. for `==`).
+var (
+	NULL  = &Null{}
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+)
+
+// HashKey identifies a hashable value by type and contents, so two objects with the same HashKey are interchangeable
+// as keys of a cache or hash. Including the type keeps e.g. an integer and a boolean from ever colliding.
+
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by the objects that can be used as keys.
+
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
 type Null struct{}
 
 func (n *Null) Type() ObjectType { return NULL_OBJ }
@@ -49,12 +137,59 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// ErrorKind classifies an Error, so callers (and tests) can check what went wrong without matching on the message.
+
+type ErrorKind string
+
+const (
+	TypeMismatch      ErrorKind = "TYPE_MISMATCH"      // operands or arguments of the wrong type
+	UnknownOperator   ErrorKind = "UNKNOWN_OPERATOR"   // an operator that isn't defined for its operand types
+	UnknownIdentifier ErrorKind = "UNKNOWN_IDENTIFIER" // a name that isn't bound
+	DivByZero         ErrorKind = "DIV_BY_ZERO"        // integer division by zero
+	Arity             ErrorKind = "ARITY"              // a call with the wrong number of arguments
+	NotCallable       ErrorKind = "NOT_CALLABLE"       // calling something that isn't a function
+	OutputLimit       ErrorKind = "OUTPUT_LIMIT"       // a program printed more than it's allowed to
+)
+
+// Error is a runtime error. Cause, when set, is the error that led to this one; Inspect() renders the whole chain,
+// outermost first, the way Go's wrapped errors read: "ERROR: calling f: division by zero".
+
 type Error struct {
+	Kind    ErrorKind
 	Message string
+	Cause   *Error
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string  { return "ERROR: " + e.Chain() }
+
+// Chain() returns the messages of the error and all of its causes joined with ": ", e.g. "calling f: division by
+// zero". It's Inspect() without the "ERROR: " prefix, for host code reporting the error as a Go error.
+
+func (e *Error) Chain() string {
+	var out strings.Builder
+
+	out.WriteString(e.Message)
+	for cause := e.Cause; cause != nil; cause = cause.Cause {
+		out.WriteString(": ")
+		out.WriteString(cause.Message)
+	}
+
+	return out.String()
+}
+
+// Wrap() returns a new error with the given message whose cause is err. The new error keeps err's kind, so checking
+// the kind of a wrapped error still tells what originally went wrong.
+
+func Wrap(err *Error, message string) *Error {
+	return &Error{Kind: err.Kind, Message: message, Cause: err}
+}
+
+// Unwrap() returns the error's cause, or nil if it has none.
+
+func (e *Error) Unwrap() *Error {
+	return e.Cause
+}
 
 type Environment struct {
 	store map[string]Object
@@ -85,6 +220,42 @@ func (e *Environment) Set(name string, val Object) Object {
 	return val
 }
 
+// Assign() rebinds an existing name in the innermost environment that has it, unlike Set(), which always binds in
+// this environment. It reports whether the name was bound anywhere.
+
+func (e *Environment) Assign(name string, val Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false
+}
+
+// Delete() removes the binding for name from this environment only; bindings with the same name in outer environments
+// are left untouched (and become visible again). It reports whether there was a binding to remove.
+
+func (e *Environment) Delete(name string) bool {
+	if _, ok := e.store[name]; !ok {
+		return false
+	}
+	delete(e.store, name)
+	return true
+}
+
+// Names() returns the names bound in this environment, not counting outer environments, in sorted order.
+
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
@@ -109,3 +280,25 @@ func (f *Function) Inspect() string {
 
 	return out.String()
 }
+
+// BuiltinFunction is the signature of functions implemented in Go and exposed to Monkey programs. ctx is the
+// evaluation making the call.
+
+type BuiltinFunction func(ctx BuiltinContext, args ...Object) Object
+
+// BuiltinContext is what a builtin gets to see of the evaluation calling it.
+
+type BuiltinContext interface {
+	// Apply calls a Monkey function or builtin with the same settings as the calling evaluation.
+	Apply(fn Object, args []Object) Object
+
+	// Output is where the calling evaluation's output goes.
+	Output() io.Writer
+}
+
+type Builtin struct {
+	Fn BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string  { return "builtin function" }
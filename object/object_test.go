@@ -0,0 +1,198 @@
+package object
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		op       func(*Integer, Object) (Object, error)
+		left     int64
+		right    int64
+		expected int64
+	}{
+		{(*Integer).Add, 2, 3, 5},
+		{(*Integer).Add, math.MaxInt64, 1, math.MinInt64}, // wraps around like int64
+		{(*Integer).Sub, 2, 3, -1},
+		{(*Integer).Sub, math.MinInt64, 1, math.MaxInt64},
+		{(*Integer).Mul, -4, 3, -12},
+		{(*Integer).Div, 7, 2, 3},
+		{(*Integer).Div, -7, 2, -3}, // truncates towards zero
+	}
+
+	for i, tt := range tests {
+		result, err := tt.op(&Integer{Value: tt.left}, &Integer{Value: tt.right})
+		if err != nil {
+			t.Errorf("tests[%d] - unexpected error: %s", i, err)
+			continue
+		}
+
+		integer, ok := result.(*Integer)
+		if !ok {
+			t.Errorf("tests[%d] - result is not Integer. got=%T (%+v)", i, result, result)
+			continue
+		}
+		if integer.Value != tt.expected {
+			t.Errorf("tests[%d] - wrong value. got=%d, want=%d", i, integer.Value, tt.expected)
+		}
+	}
+}
+
+func TestIntegerDivisionByZero(t *testing.T) {
+	_, err := (&Integer{Value: 5}).Div(&Integer{Value: 0})
+	if err != ErrDivisionByZero {
+		t.Errorf("wrong error. expected=%v, got=%v", ErrDivisionByZero, err)
+	}
+}
+
+func TestIntegerArithmeticTypeMismatch(t *testing.T) {
+	_, err := (&Integer{Value: 5}).Add(&Boolean{Value: true})
+	if err == nil {
+		t.Fatalf("expected an error, got nil")
+	}
+
+	expected := "type mismatch: INTEGER + BOOLEAN"
+	if err.Error() != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, err.Error())
+	}
+}
+
+func TestEnvironmentDelete(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+
+	env := NewEnclosedEnvironment(outer)
+	env.Set("x", &Integer{Value: 2})
+	env.Set("y", &Integer{Value: 3})
+
+	if !env.Delete("y") {
+		t.Errorf("Delete(y) returned false for an existing binding")
+	}
+	if _, ok := env.Get("y"); ok {
+		t.Errorf("y is still bound after Delete")
+	}
+
+	if env.Delete("missing") {
+		t.Errorf("Delete(missing) returned true for a missing binding")
+	}
+
+	// deleting the local x uncovers the outer one, which stays untouched
+	if !env.Delete("x") {
+		t.Errorf("Delete(x) returned false for an existing binding")
+	}
+	obj, ok := env.Get("x")
+	if !ok {
+		t.Fatalf("outer x is not visible after deleting the local one")
+	}
+	if obj.(*Integer).Value != 1 {
+		t.Errorf("x has wrong value. got=%d, want=1", obj.(*Integer).Value)
+	}
+
+	if env.Delete("x") {
+		t.Errorf("Delete(x) removed a binding from the outer environment")
+	}
+	if _, ok := outer.Get("x"); !ok {
+		t.Errorf("outer x was deleted")
+	}
+}
+
+func TestGoRoundTrip(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{false, false},
+		{42, int64(42)},
+		{int8(-8), int64(-8)},
+		{int16(16), int64(16)},
+		{int32(-32), int64(-32)},
+		{int64(math.MaxInt64), int64(math.MaxInt64)},
+	}
+
+	for _, tt := range tests {
+		obj, err := FromGo(tt.input)
+		if err != nil {
+			t.Errorf("FromGo(%#v) returned error: %s", tt.input, err)
+			continue
+		}
+		back, err := ToGo(obj)
+		if err != nil {
+			t.Errorf("ToGo(%s) returned error: %s", obj.Inspect(), err)
+			continue
+		}
+		if back != tt.expected {
+			t.Errorf("round trip of %#v wrong. want=%#v, got=%#v", tt.input, tt.expected, back)
+		}
+	}
+
+	if obj, _ := FromGo(true); obj != TRUE {
+		t.Errorf("FromGo(true) is not the TRUE singleton")
+	}
+	if obj, _ := FromGo(nil); obj != NULL {
+		t.Errorf("FromGo(nil) is not the NULL singleton")
+	}
+}
+
+func TestGoConversionErrors(t *testing.T) {
+	for _, v := range []interface{}{1.5, "str", []int{1, 2}, []interface{}{1, []interface{}{2}}, map[string]int{"a": 1}} {
+		if _, err := FromGo(v); err == nil {
+			t.Errorf("FromGo(%#v) returned no error", v)
+		}
+	}
+
+	if _, err := ToGo(&Error{Message: "boom"}); err == nil {
+		t.Errorf("ToGo(ERROR) returned no error")
+	}
+	if _, err := ToGo(&Builtin{}); err == nil {
+		t.Errorf("ToGo(BUILTIN) returned no error")
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	one1 := &Integer{Value: 1}
+	one2 := &Integer{Value: 1}
+	two := &Integer{Value: 2}
+
+	if one1.HashKey() != one2.HashKey() {
+		t.Errorf("integers with same content have different hash keys")
+	}
+	if one1.HashKey() == two.HashKey() {
+		t.Errorf("integers with different content have same hash keys")
+	}
+	if TRUE.HashKey() != (&Boolean{Value: true}).HashKey() || TRUE.HashKey() == FALSE.HashKey() {
+		t.Errorf("wrong boolean hash keys")
+	}
+	if one1.HashKey() == TRUE.HashKey() {
+		t.Errorf("1 and true have the same hash key")
+	}
+}
+
+func TestErrorWrapping(t *testing.T) {
+	root := &Error{Kind: DivByZero, Message: "division by zero"}
+	wrapped := Wrap(Wrap(root, "in half(0)"), "in main()")
+
+	if wrapped.Inspect() != "ERROR: in main(): in half(0): division by zero" {
+		t.Errorf("wrong Inspect() for wrapped error. got=%q", wrapped.Inspect())
+	}
+	if wrapped.Kind != DivByZero {
+		t.Errorf("wrapped error has wrong kind. want=%q, got=%q", DivByZero, wrapped.Kind)
+	}
+
+	var chain []string
+	for err := wrapped; err != nil; err = err.Unwrap() {
+		chain = append(chain, err.Message)
+	}
+	if len(chain) != 3 || chain[2] != root.Message || wrapped.Unwrap().Unwrap() != root {
+		t.Errorf("wrong cause chain. got=%q", chain)
+	}
+
+	if wrapped.Chain() != "in main(): in half(0): division by zero" {
+		t.Errorf("wrong Chain() for wrapped error. got=%q", wrapped.Chain())
+	}
+	if root.Inspect() != "ERROR: division by zero" {
+		t.Errorf("wrong Inspect() for unwrapped error. got=%q", root.Inspect())
+	}
+}
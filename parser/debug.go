@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"monkey/ast"
+	"strings"
+)
+
+// DebugTree() renders an expression as an indented tree, one node per line, with every operator tagged with the
+// precedence it was parsed at. Operands sit one level below their operator, so the operator that binds tighter is the
+// one further down:
+//
+//	+ [SUM]
+//	  1
+//	  * [PRODUCT]
+//	    2
+//	    3
+//
+// Anything that isn't an operator (literals, identifiers, function literals, ...) is printed with String().
+
+func DebugTree(expr ast.Expression) string {
+	var out strings.Builder
+	writeDebugTree(&out, expr, 0)
+	return out.String()
+}
+
+func writeDebugTree(out *strings.Builder, expr ast.Expression, depth int) {
+	out.WriteString(strings.Repeat("  ", depth))
+
+	switch expr := expr.(type) {
+	case *ast.InfixExpression:
+		out.WriteString(expr.Operator + " [" + precedenceNames[precedences[expr.Token.Type]] + "]\n")
+		writeDebugTree(out, expr.Left, depth+1)
+		writeDebugTree(out, expr.Right, depth+1)
+	case *ast.PrefixExpression:
+		out.WriteString(expr.Operator + " [" + precedenceNames[PREFIX] + "]\n")
+		writeDebugTree(out, expr.Right, depth+1)
+	case *ast.CallExpression:
+		out.WriteString("call [" + precedenceNames[CALL] + "]\n")
+		writeDebugTree(out, expr.Function, depth+1)
+		for _, arg := range expr.Arguments {
+			writeDebugTree(out, arg, depth+1)
+		}
+	case nil:
+		out.WriteString("<nil>\n")
+	default:
+		out.WriteString(expr.String() + "\n")
+	}
+}
@@ -11,30 +11,70 @@ import (
 type Parser struct {
 	l              *lexer.Lexer
 	errors         []string
+	errorTokens    []token.Token // the token each of errors was reported at
 	currToken      token.Token
 	peekToken      token.Token
+	peek2Token     token.Token                       // the token after peekToken, for two-token lookahead
 	prefixParseFns map[token.TokenType]prefixParseFn // map of functions that can parse a prefix token
 	infixParseFns  map[token.TokenType]infixParseFn  // map of functions that can parse an infix token
+
+	depth          int // current nesting depth of expressions and blocks
+	maxDepth       int // see WithMaxNestingDepth()
+	tooDeep        bool
+	tooDeepErrorAt int // number of errors recorded when the nesting limit was hit
+
+	strictSemicolons bool // see WithStrictSemicolons()
+}
+
+// DefaultMaxNestingDepth is the nesting limit used unless WithMaxNestingDepth() says otherwise. Real programs come
+// nowhere near it, while it's still far away from overflowing the Go stack.
+const DefaultMaxNestingDepth = 250
+
+// Option configures optional parser behavior. Options are passed to New().
+
+type Option func(*Parser)
+
+// WithMaxNestingDepth() sets how deeply expressions and blocks may nest, e.g. `((((1))))` nests 5 expressions deep.
+// Beyond the limit the parser reports "expression nesting too deep" and gives up on the rest of the input instead of
+// recursing until the Go stack overflows.
+
+func WithMaxNestingDepth(depth int) Option {
+	return func(p *Parser) { p.maxDepth = depth }
+}
+
+// WithStrictSemicolons() makes the semicolon at the end of a statement mandatory, for every statement including the last
+// one in a block or program. By default it's optional wherever the statement ends unambiguously.
+
+func WithStrictSemicolons() Option {
+	return func(p *Parser) { p.strictSemicolons = true }
 }
 
-func New(l *lexer.Lexer) *Parser {
+func New(l *lexer.Lexer, opts ...Option) *Parser {
 	p := &Parser{l: l,
-		errors: []string{},
+		errors:   []string{},
+		maxDepth: DefaultMaxNestingDepth,
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
-	// Read two tokens, so currToken and peekToken are both set
+	// Read three tokens, so currToken, peekToken and peek2Token are all set
+	p.nextToken()
 	p.nextToken()
 	p.nextToken()
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
-	p.registerPrefix(token.BANG, p.parsePrefixExpression)
-	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	for _, operator := range prefixOperators {
+		p.registerPrefix(operator, p.parsePrefixExpression)
+	}
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.AT, p.parseDecoratorExpression)
+	p.registerPrefix(token.LET, p.parseLetInExpression)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -50,63 +90,261 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
-// nextToken() advances both currToken and peekToken. It's called twice in the constructor function New() to set both
-// currToken and peekToken.
+// nextToken() advances currToken, peekToken and peek2Token. It's called three times in the constructor function New()
+// to set all of them.
 
 func (p *Parser) nextToken() {
 	p.currToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	p.peekToken = p.peek2Token
+	p.peek2Token = p.l.NextToken()
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{} // create a new Program node
 	program.Statements = []ast.Statement{}
 
+	for {
+		stmt, ok := p.NextStatement()
+		if !ok {
+			break
+		}
+		program.Statements = append(program.Statements, stmt) // add the statement to the program
+	}
+	return program
+}
+
+// NextStatement() parses and returns the next top-level statement, so a large program can be parsed (and evaluated)
+// one statement at a time instead of being held in memory as a whole. It returns false once the input is used up.
+// Statements that fail to parse are skipped, as in ParseProgram(); check Errors() to find out about them.
+
+func (p *Parser) NextStatement() (ast.Statement, bool) {
 	for !p.currTokenIs(token.EOF) {
 		stmt := p.parseStatement()
+		if p.tooDeep {
+			// everything reported while unwinding from the nesting limit is just noise
+			p.errors = p.errors[:p.tooDeepErrorAt]
+			p.errorTokens = p.errorTokens[:p.tooDeepErrorAt]
+			return nil, false
+		}
+		p.nextToken()
 		if stmt != nil {
-			program.Statements = append(program.Statements, stmt) // add the statement to the program
+			return stmt, true
 		}
+	}
+	return nil, false
+}
+
+// enterNesting() is called on entry to every construct that can nest, and leaveNesting() on exit. When the limit is
+// exceeded it records the error and skips to the end of the input, so every parse function on the way back up
+// finishes immediately instead of descending any further.
+
+func (p *Parser) enterNesting() bool {
+	p.depth++
+	if p.depth <= p.maxDepth {
+		return true
+	}
+
+	if !p.tooDeep {
+		p.tooDeep = true
+		p.errorAt(p.currToken, "expression nesting too deep")
+		p.tooDeepErrorAt = len(p.errors)
+	}
+	for !p.currTokenIs(token.EOF) {
 		p.nextToken()
 	}
-	return program
+	return false
+}
+
+func (p *Parser) leaveNesting() {
+	p.depth--
 }
 
 // parseStatement() is the heart of our parser. It's responsible for parsing a statement. It's also responsible for
-// advancing our two pointers p.currToken and p.peekToken.
+// advancing our two pointers p.currToken and p.peekToken. The statement parsers return a nil pointer on failure,
+// which has to become a plain nil here: stored in the ast.Statement interface, it wouldn't compare equal to nil.
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.currToken.Type {
 	case token.LET:
+		if p.peekTokenIs(token.IDENT) && p.peekTokenIs2(token.COMMA) {
+			if stmt := p.parseMultiLetStatement(); stmt != nil {
+				return stmt
+			}
+			return nil
+		}
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.IDENT:
+		// no expression starts with `identifier ,`, so this can only be a multiple assignment
+		if p.peekTokenIs(token.COMMA) {
+			if stmt := p.parseMultiAssignStatement(); stmt != nil {
+				return stmt
+			}
+			return nil
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
-func (p *Parser) parseLetStatement() *ast.LetStatement {
-	stmt := &ast.LetStatement{Token: p.currToken}
+// endStatement() consumes the semicolon that ends a statement. Without strict semicolons a missing one is fine.
+
+func (p *Parser) endStatement() {
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		return
+	}
+	if p.strictSemicolons {
+		p.peekError(token.SEMICOLON)
+	}
+}
+
+// parseLetStatement() parses `let x = value;`. Only once the value has been parsed can it tell a let statement from a
+// let-in expression, so when the value is followed by `in` it finishes the let-in and returns it as an expression
+// statement instead.
 
+func (p *Parser) parseLetStatement() ast.Statement {
+	letToken := p.currToken
+
+	name, value, ok := p.parseLetBinding()
+	if !ok {
+		return nil
+	}
+
+	if p.peekTokenIs(token.IN) {
+		p.nextToken()
+		stmt := &ast.ExpressionStatement{Token: letToken, Expression: p.parseLetInBody(letToken, name, value)}
+		p.endStatement()
+		return stmt
+	}
+
+	p.endStatement()
+
+	return &ast.LetStatement{Token: letToken, Name: name, Value: value}
+}
+
+// parseLetInExpression() parses a let-in in expression position, e.g. the inner one of
+// `let x = 1 in let y = 2 in x + y` or the operand in `1 + let x = 2 in x`.
+
+func (p *Parser) parseLetInExpression() ast.Expression {
+	letToken := p.currToken
+
+	name, value, ok := p.parseLetBinding()
+	if !ok || !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	return p.parseLetInBody(letToken, name, value)
+}
+
+// parseLetBinding() parses the `x = value` part shared by let statements and let-in expressions. It starts on the LET
+// token and stops on the value's last token.
+
+func (p *Parser) parseLetBinding() (*ast.Identifier, ast.Expression, bool) {
 	if !p.expectPeek(token.IDENT) {
+		return nil, nil, false
+	}
+
+	name := &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil, nil, false
+	}
+
+	p.nextToken()
+	return name, p.parseExpression(LOWEST), true
+}
+
+// parseLetInBody() parses the body of a let-in, starting on the IN token. The body extends as far right as possible,
+// like a function body would without braces.
+
+func (p *Parser) parseLetInBody(letToken token.Token, name *ast.Identifier, value ast.Expression) ast.Expression {
+	p.nextToken()
+	return &ast.LetInExpression{Token: letToken, Name: name, Value: value, Body: p.parseExpression(LOWEST)}
+}
+
+func (p *Parser) parseMultiLetStatement() *ast.MultiLetStatement {
+	stmt := &ast.MultiLetStatement{Token: p.currToken}
+
+	p.nextToken()
+	stmt.Names = p.parseIdentifierList()
+	if stmt.Names == nil {
 		return nil
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal}
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	stmt.Values = p.parseExpressionList()
+	if stmt.Values == nil {
+		return nil
+	}
+
+	p.endStatement()
+
+	return stmt
+}
+
+func (p *Parser) parseMultiAssignStatement() *ast.MultiAssignStatement {
+	stmt := &ast.MultiAssignStatement{Token: p.currToken}
+
+	stmt.Targets = p.parseIdentifierList()
+	if stmt.Targets == nil {
+		return nil
+	}
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
 
+	stmt.Values = p.parseExpressionList()
+	if stmt.Values == nil {
+		return nil
+	}
+
+	p.endStatement()
+
+	return stmt
+}
+
+// parseIdentifierList() parses `a, b, c` starting at the first identifier and stops on the last one.
+
+func (p *Parser) parseIdentifierList() []*ast.Identifier {
+	identifiers := []*ast.Identifier{{Token: p.currToken, Value: p.currToken.Literal}}
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		identifiers = append(identifiers, &ast.Identifier{Token: p.currToken, Value: p.currToken.Literal})
+	}
+
+	return identifiers
+}
+
+// parseExpressionList() parses the comma-separated expressions that follow the current token. It returns nil if any of
+// them fails to parse, e.g. after a trailing comma.
+
+func (p *Parser) parseExpressionList() []ast.Expression {
 	p.nextToken()
-	stmt.Value = p.parseExpression(LOWEST)
+	list := []ast.Expression{p.parseExpression(LOWEST)}
 
-	if p.peekTokenIs(token.SEMICOLON) {
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
 		p.nextToken()
+		list = append(list, p.parseExpression(LOWEST))
 	}
 
-	return stmt
+	for _, exp := range list {
+		if exp == nil {
+			return nil
+		}
+	}
+	return list
 }
 
 func (p *Parser) currTokenIs(t token.TokenType) bool {
@@ -117,6 +355,12 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 	return p.peekToken.Type == t
 }
 
+// peekTokenIs2() looks two tokens ahead. Some grammar decisions can't be made from peekToken alone, e.g. telling a
+// block `{ x }` apart from a hash `{ x: 1 }` requires seeing the token that follows the first one inside the brace.
+func (p *Parser) peekTokenIs2(t token.TokenType) bool {
+	return p.peek2Token.Type == t
+}
+
 // expectPeek() is a helper function that makes our parser more robust. It checks the type of the next token. If the
 // next token is of the expected type, it advances the tokens and returns true.
 // If the next token is not of the expected type, it returns false.
@@ -134,22 +378,42 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// ErrorTokens() returns the token each error in Errors() was reported at, in the same order, so a caller can point at
+// the line and column where parsing went wrong.
+
+func (p *Parser) ErrorTokens() []token.Token {
+	return p.errorTokens
+}
+
+// errorAt() records the error msg, found at tok.
+
+func (p *Parser) errorAt(tok token.Token, msg string) {
+	p.errors = append(p.errors, msg)
+	p.errorTokens = append(p.errorTokens, tok)
+}
+
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
 		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.peekToken, msg)
 }
 
+// parseReturnStatement() leaves ReturnValue nil for a bare `return;`, which returns null. The value can also be left
+// out when the return is the last thing in a block or in the program.
+
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.currToken}
 
+	if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+		p.endStatement()
+		return stmt
+	}
+
 	p.nextToken()
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	if p.peekTokenIs(token.SEMICOLON) {
-		p.nextToken()
-	}
+	p.endStatement()
 
 	return stmt
 }
@@ -171,9 +435,7 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.currToken} // create a new ExpressionStatement node and set its token
 	stmt.Expression = p.parseExpression(LOWEST)          // parse the expression
 
-	if p.peekTokenIs(token.SEMICOLON) { // check if the next token is a semicolon
-		p.nextToken() // if it is, advance the tokens
-	}
+	p.endStatement() // skip the semicolon, if there is one
 
 	return stmt
 }
@@ -203,13 +465,18 @@ var precedences = map[token.TokenType]int{
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.currToken, msg)
 }
 
 // parseExpression() is the heart of our Pratt parser. It's responsible for parsing an expression. It's also responsible
 // for advancing our two pointers p.currToken and p.peekToken.
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	if !p.enterNesting() {
+		return nil
+	}
+	defer p.leaveNesting()
+
 	prefix := p.prefixParseFns[p.currToken.Type] // look up the prefixParseFn for the current token type
 	if prefix == nil {
 		p.noPrefixParseFnError(p.currToken.Type)
@@ -240,7 +507,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	value, err := strconv.ParseInt(p.currToken.Literal, 0, 64)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.currToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.currToken, msg)
 		return nil
 	}
 
@@ -316,6 +583,13 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	p.nextToken()
 	expression.Condition = p.parseExpression(LOWEST)
 
+	// `if (x = 5)` is almost always a typo for `if (x == 5)`. Assignment isn't an expression, so this would fail
+	// anyway, but with a much less helpful "expected next token to be )" error.
+	if p.peekTokenIs(token.ASSIGN) {
+		p.errorAt(p.peekToken, "unexpected '=' in condition, did you mean '=='?")
+		return nil
+	}
+
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
@@ -329,6 +603,15 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			expression.Alternative = p.parseElseIfBlock()
+			if expression.Alternative == nil {
+				return nil
+			}
+			return expression
+		}
+
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
@@ -339,7 +622,34 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// parseElseIfBlock() parses the `if` of an `else if` chain and wraps it in a block holding that single if expression,
+// so `else if (a) { x }` ends up with exactly the same AST as `else { if (a) { x } }` and the evaluator doesn't need to
+// know about chains at all.
+
+func (p *Parser) parseElseIfBlock() *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: p.currToken}
+
+	if !p.enterNesting() {
+		return nil
+	}
+	defer p.leaveNesting()
+
+	stmt := &ast.ExpressionStatement{Token: p.currToken}
+	stmt.Expression = p.parseIfExpression()
+	if stmt.Expression == nil {
+		return nil
+	}
+
+	block.Statements = []ast.Statement{stmt}
+	return block
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	if !p.enterNesting() {
+		return nil
+	}
+	defer p.leaveNesting()
+
 	block := &ast.BlockStatement{Token: p.currToken}
 	block.Statements = []ast.Statement{}
 
@@ -430,3 +740,45 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 
 	return args
 }
+
+// parseDecoratorExpression() parses `@name fn(...) {...}` and `@name(args) fn(...) {...}`. Only function literals (or
+// further decorators) can be decorated; the decorated function isn't parsed with parseExpression() so that a call
+// directly following it, as in `@dec fn(x) { x }(5)`, doesn't end up being decorated instead.
+
+func (p *Parser) parseDecoratorExpression() ast.Expression {
+	expression := &ast.DecoratorExpression{Token: p.currToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	expression.Decorator = p.parseIdentifier()
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		expression.Decorator = p.parseCallExpression(expression.Decorator)
+	}
+
+	p.nextToken()
+
+	switch p.currToken.Type {
+	case token.FUNCTION:
+		expression.Function = p.parseFunctionLiteral()
+	case token.AT:
+		// stacked decorators recurse without going through parseExpression(), so they count towards the limit here
+		if !p.enterNesting() {
+			return nil
+		}
+		expression.Function = p.parseDecoratorExpression()
+		p.leaveNesting()
+	default:
+		msg := fmt.Sprintf("expected function literal after decorator, got %s instead", p.currToken.Type)
+		p.errorAt(p.currToken, msg)
+		return nil
+	}
+
+	if expression.Function == nil {
+		return nil
+	}
+
+	return expression
+}
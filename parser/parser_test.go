@@ -3,7 +3,10 @@ package parser
 import (
 	"fmt"
 	"monkey/ast"
+	"monkey/internal/testutil"
 	"monkey/lexer"
+	"monkey/token"
+	"strings"
 	"testing"
 )
 
@@ -41,6 +44,96 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestCaseInsensitiveLetStatement(t *testing.T) {
+	input := "Let x = 5;"
+
+	l := lexer.New(input, lexer.WithCaseInsensitiveKeywords())
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.LetStatement. got=%T", program.Statements[0])
+	}
+	if stmt.TokenLiteral() != "Let" {
+		t.Errorf("stmt.TokenLiteral not %q. got=%q", "Let", stmt.TokenLiteral())
+	}
+	if stmt.Name.Value != "x" {
+		t.Errorf("stmt.Name.Value not 'x'. got=%s", stmt.Name.Value)
+	}
+
+	// in the default case-sensitive mode `Let` is just an identifier
+	l = lexer.New(input)
+	p = New(l)
+	program = p.ParseProgram()
+
+	if _, ok := program.Statements[0].(*ast.LetStatement); ok {
+		t.Fatalf("Let parsed as a let statement in case-sensitive mode")
+	}
+}
+
+func TestMultipleAssignmentParsing(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedTargets []string
+		expectedValues  []string
+		expectedString  string
+	}{
+		{"let a, b = 1, 2;", []string{"a", "b"}, []string{"1", "2"}, "let a, b = 1, 2;"},
+		{"let x, y, z = 1 + 2, f(3), true", []string{"x", "y", "z"}, []string{"(1 + 2)", "f(3)", "true"},
+			"let x, y, z = (1 + 2), f(3), true;"},
+		{"a, b = b, a;", []string{"a", "b"}, []string{"b", "a"}, "a, b = b, a;"},
+		{"a, b = 1;", []string{"a", "b"}, []string{"1"}, "a, b = 1;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		var targets []*ast.Identifier
+		var values []ast.Expression
+		switch stmt := program.Statements[0].(type) {
+		case *ast.MultiLetStatement:
+			targets, values = stmt.Names, stmt.Values
+		case *ast.MultiAssignStatement:
+			targets, values = stmt.Targets, stmt.Values
+		default:
+			t.Fatalf("stmt is not a multiple assignment. got=%T", stmt)
+		}
+
+		if len(targets) != len(tt.expectedTargets) {
+			t.Fatalf("wrong number of targets. want=%d, got=%d", len(tt.expectedTargets), len(targets))
+		}
+		for i, name := range tt.expectedTargets {
+			testIdentifier(t, targets[i], name)
+		}
+
+		if len(values) != len(tt.expectedValues) {
+			t.Fatalf("wrong number of values. want=%d, got=%d", len(tt.expectedValues), len(values))
+		}
+		for i, value := range tt.expectedValues {
+			if values[i].String() != value {
+				t.Errorf("values[%d] wrong. want=%q, got=%q", i, value, values[i].String())
+			}
+		}
+
+		if program.String() != tt.expectedString {
+			t.Errorf("program.String() wrong. want=%q, got=%q", tt.expectedString, program.String())
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -77,6 +170,36 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
+func TestBareReturnStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"return;", "return;"},
+		{"return", "return;"},
+		{"fn() { return; }", "fn()return;"},
+		{"fn() { return }", "fn()return;"},
+		{"fn(x) { if (x) { return; } x }", "fn(x)ifx return;x"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong for %q. want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	program := New(lexer.New("return;")).ParseProgram()
+	returnStmt := program.Statements[0].(*ast.ReturnStatement)
+	if returnStmt.ReturnValue != nil {
+		t.Errorf("returnStmt.ReturnValue not nil. got=%T", returnStmt.ReturnValue)
+	}
+}
+
 func TestIdentifierExpression(t *testing.T) {
 	input := "foobar;"
 
@@ -108,6 +231,39 @@ func TestIdentifierExpression(t *testing.T) {
 	}
 }
 
+func TestPrefixedIntegerLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xFF;", 255},
+		{"0x1a2b;", 0x1a2b},
+		{"0X10;", 16},
+		{"0x7fffffffffffffff;", 1<<63 - 1},
+		{"0b1010;", 10},
+		{"0o755;", 0755},
+		{"0O17;", 15},
+		{"1_000_000;", 1000000},
+		{"0xFF_FF;", 0xFFFF},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value wrong for %q. want=%d, got=%d", tt.input, tt.expected, literal.Value)
+		}
+	}
+}
+
 func TestIntegerLiteralExpression(t *testing.T) {
 	input := "5;"
 
@@ -501,6 +657,74 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x < y) { x } else if (x > y) { y } else { z }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+
+	if exp.Alternative == nil || len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("exp.Alternative does not contain 1 statement. got=%+v", exp.Alternative)
+	}
+
+	alternative, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T",
+			exp.Alternative.Statements[0])
+	}
+
+	elseIf, ok := alternative.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("alternative is not ast.IfExpression. got=%T", alternative.Expression)
+	}
+
+	if !testInfixExpression(t, elseIf.Condition, "x", ">", "y") {
+		return
+	}
+
+	if elseIf.Alternative == nil || len(elseIf.Alternative.Statements) != 1 {
+		t.Fatalf("elseIf.Alternative does not contain 1 statement. got=%+v", elseIf.Alternative)
+	}
+
+	expected := "if(x < y) xelse if(x > y) yelse z"
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestAssignmentInCondition(t *testing.T) {
+	l := lexer.New("if (x = 5) {}")
+	p := New(l)
+	p.ParseProgram()
+
+	expected := "unexpected '=' in condition, did you mean '=='?"
+	if len(p.Errors()) == 0 || p.Errors()[0] != expected {
+		t.Fatalf("wrong parser errors. want first=%q, got=%v", expected, p.Errors())
+	}
+
+	l = lexer.New("if (x == 5) {}")
+	p = New(l)
+	p.ParseProgram()
+	checkParserErrors(t, p)
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -670,6 +894,181 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestLetInExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedName   string
+		expectedValue  string
+		expectedBody   string
+		expectedString string
+	}{
+		{"let x = 5 in x + 1;", "x", "5", "(x + 1)", "let x = 5 in (x + 1)"},
+		{"let x = 1 in let y = 2 in x * y", "x", "1", "let y = 2 in (x * y)", "let x = 1 in let y = 2 in (x * y)"},
+		{"let f = fn(a) { a } in f(3)", "f", "fn(a)a", "f(3)", "let f = fn(a)a in f(3)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+		exp, ok := stmt.Expression.(*ast.LetInExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.LetInExpression. got=%T", stmt.Expression)
+		}
+
+		if exp.Name.Value != tt.expectedName {
+			t.Errorf("name wrong. want=%q, got=%q", tt.expectedName, exp.Name.Value)
+		}
+		if exp.Value.String() != tt.expectedValue {
+			t.Errorf("value wrong. want=%q, got=%q", tt.expectedValue, exp.Value.String())
+		}
+		if exp.Body.String() != tt.expectedBody {
+			t.Errorf("body wrong. want=%q, got=%q", tt.expectedBody, exp.Body.String())
+		}
+		if program.String() != tt.expectedString {
+			t.Errorf("program.String() wrong. want=%q, got=%q", tt.expectedString, program.String())
+		}
+	}
+
+	// in expression position the `in` is required
+	p := New(lexer.New("1 + let x = 2;"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be IN, got ; instead" {
+		t.Errorf("wrong errors for a let-in without in. got=%q", p.Errors())
+	}
+}
+
+func TestDecoratorExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input             string
+		expectedDecorator string
+		expectedString    string
+	}{
+		{"@memoize fn(n) { n };", "memoize", "@memoize fn(n)n"},
+		{"@retry(3, x) fn() { x };", "retry(3, x)", "@retry(3, x) fn()x"},
+		{"@outer @inner fn(x) { x };", "outer", "@outer @inner fn(x)x"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d", len(program.Statements))
+		}
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.DecoratorExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.DecoratorExpression. got=%T", stmt.Expression)
+		}
+
+		if exp.Decorator.String() != tt.expectedDecorator {
+			t.Errorf("decorator wrong. want=%q, got=%q", tt.expectedDecorator, exp.Decorator.String())
+		}
+
+		switch exp.Function.(type) {
+		case *ast.FunctionLiteral, *ast.DecoratorExpression:
+		default:
+			t.Errorf("decorated expression is not a function literal or decorator. got=%T", exp.Function)
+		}
+
+		if program.String() != tt.expectedString {
+			t.Errorf("program.String() wrong. want=%q, got=%q", tt.expectedString, program.String())
+		}
+	}
+}
+
+func TestDecoratorCallIsNotDecorated(t *testing.T) {
+	l := lexer.New("@dec fn(x) { x }(5)")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	call, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+	}
+	if _, ok := call.Function.(*ast.DecoratorExpression); !ok {
+		t.Fatalf("call.Function is not ast.DecoratorExpression. got=%T", call.Function)
+	}
+}
+
+func TestDecoratorWithoutFunction(t *testing.T) {
+	l := lexer.New("@dec 5")
+	p := New(l)
+	p.ParseProgram()
+
+	expected := "expected function literal after decorator, got INT instead"
+	if len(p.Errors()) == 0 || p.Errors()[0] != expected {
+		t.Fatalf("wrong parser errors. want first=%q, got=%v", expected, p.Errors())
+	}
+}
+
+func TestErrorTokens(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string // "line:column literal" for each error
+	}{
+		{"let x 5;", []string{"1:7 5"}},
+		{"let x = 1;\n  let = 2;", []string{"2:7 =", "2:7 ="}},
+		{"x + ;", []string{"1:5 ;"}},
+		{"if (x = 1);", []string{"1:7 =", "1:7 =", "1:10 )"}},
+		{"@dec 5", []string{"1:6 5"}},
+		{"let y = 99999999999999999999;", []string{"1:9 99999999999999999999"}},
+		{"(((1)))", []string{"1:4 1"}},
+		{"((1))", nil},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l, WithMaxNestingDepth(3))
+		p.ParseProgram()
+
+		if len(p.ErrorTokens()) != len(p.Errors()) {
+			t.Fatalf("%d error tokens for %d errors for %q", len(p.ErrorTokens()), len(p.Errors()), tt.input)
+		}
+		var got []string
+		for _, tok := range p.ErrorTokens() {
+			got = append(got, fmt.Sprintf("%d:%d %s", tok.Line, tok.Column, tok.Literal))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+			t.Errorf("wrong error tokens for %q. want=%q, got=%q (%q)", tt.input, tt.expected, got, p.Errors())
+		}
+	}
+}
+
+func TestMultipleAssignmentTrailingComma(t *testing.T) {
+	for _, input := range []string{"let a, b = 1, ;", "a, b = 1, ;"} {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("no parser errors for %q", input)
+		}
+		for _, stmt := range program.Statements {
+			switch stmt.(type) {
+			case *ast.MultiLetStatement, *ast.MultiAssignStatement:
+				t.Errorf("incomplete statement kept for %q: %s", input, stmt.String())
+			}
+		}
+	}
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
 		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
@@ -815,3 +1214,258 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 	t.FailNow()
 }
+
+func TestNextStatement(t *testing.T) {
+	input := `
+let x = 5;
+let add = fn(a, b) { a + b };
+;
+return add(x, 10);
+if (x > 1) { x } else { 0 }
+let = 1;
+x, y = y, x;
+`
+
+	p := New(lexer.New(input))
+	var streamed []string
+	for {
+		stmt, ok := p.NextStatement()
+		if !ok {
+			break
+		}
+		streamed = append(streamed, stmt.String())
+	}
+
+	if _, ok := p.NextStatement(); ok {
+		t.Errorf("NextStatement() returned a statement after the end of the input")
+	}
+
+	whole := New(lexer.New(input))
+	program := whole.ParseProgram()
+
+	if len(streamed) != len(program.Statements) {
+		t.Fatalf("wrong number of statements. want=%d, got=%d (%q)", len(program.Statements), len(streamed), streamed)
+	}
+	for i, stmt := range program.Statements {
+		if streamed[i] != stmt.String() {
+			t.Errorf("statement %d wrong. want=%q, got=%q", i, stmt.String(), streamed[i])
+		}
+	}
+
+	if strings.Join(p.Errors(), "\n") != strings.Join(whole.Errors(), "\n") || len(p.Errors()) == 0 {
+		t.Errorf("errors differ.\nstreamed=%q\nwhole=   %q", p.Errors(), whole.Errors())
+	}
+}
+
+func TestNestingDepthLimit(t *testing.T) {
+	deep := strings.Repeat("(", 100000) + "1" + strings.Repeat(")", 100000)
+
+	tests := []struct {
+		input    string
+		opts     []Option
+		tooDeep  bool
+		expected string
+	}{
+		{deep, nil, true, ""},
+		{"let x = " + deep + ";", nil, true, ""},
+		{strings.Repeat("-", 100000) + "1", nil, true, ""},
+		{strings.Repeat("(", 200) + "1" + strings.Repeat(")", 200), nil, false, "1"},
+		{"((((1))))", []Option{WithMaxNestingDepth(5)}, false, "1"},
+		{"(((((1)))))", []Option{WithMaxNestingDepth(5)}, true, ""},
+		{"fn() { fn() { 1 } }", []Option{WithMaxNestingDepth(5)}, false, "fn()fn()1"},
+		{"fn() { fn() { 1 } }", []Option{WithMaxNestingDepth(4)}, true, ""},
+		{"@a @b fn() { 1 }", []Option{WithMaxNestingDepth(5)}, false, "@a @b fn()1"},
+		{strings.Repeat("@a ", 50) + "fn() { 1 }", []Option{WithMaxNestingDepth(5)}, true, ""},
+		{strings.Repeat("@a ", 100000) + "fn() { 1 }", nil, true, ""},
+		{"if (a) { 1 } else if (b) { 2 }", []Option{WithMaxNestingDepth(5)}, false, "ifa 1else ifb 2"},
+		{"if (a) { 1 }" + strings.Repeat(" else if (a) { 1 }", 50), []Option{WithMaxNestingDepth(5)}, true, ""},
+		{"if (a) { 1 }" + strings.Repeat(" else if (a) { 1 }", 100000), nil, true, ""},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l, tt.opts...)
+		program := p.ParseProgram()
+
+		if !tt.tooDeep {
+			checkParserErrors(t, p)
+			if program.String() != tt.expected {
+				t.Errorf("program.String() wrong. want=%q, got=%q", tt.expected, program.String())
+			}
+			continue
+		}
+
+		errors := p.Errors()
+		if len(errors) != 1 || errors[0] != "expression nesting too deep" {
+			t.Errorf("wrong parser errors for input of length %d. got=%d errors, first few: %.3q",
+				len(tt.input), len(errors), errors)
+		}
+	}
+}
+
+func TestTokenWindow(t *testing.T) {
+	input := "let add = fn(a, b) {\n  a + b; // sum\n};\nadd(1, 2)"
+
+	// stepping through the parser's token window must visit exactly the tokens the lexer produces
+	p := New(lexer.New(input))
+	var seen []token.Token
+	for {
+		seen = append(seen, p.currToken)
+		if p.currTokenIs(token.EOF) {
+			break
+		}
+		p.nextToken()
+	}
+
+	testutil.AssertTokens(t, input, lexer.New(input).Tokenize(), seen)
+}
+
+func TestTwoTokenLookahead(t *testing.T) {
+	l := lexer.New("let x = { y };")
+	p := New(l)
+
+	if !p.currTokenIs(token.LET) || !p.peekTokenIs(token.IDENT) || !p.peekTokenIs2(token.ASSIGN) {
+		t.Fatalf("lookahead not initialized. got curr=%q peek=%q peek2=%q",
+			p.currToken.Type, p.peekToken.Type, p.peek2Token.Type)
+	}
+
+	// advance so that currToken is '=' and peekToken is '{'
+	p.nextToken()
+	p.nextToken()
+
+	if !p.peekTokenIs(token.LBRACE) {
+		t.Fatalf("peekToken is not LBRACE. got=%q", p.peekToken.Type)
+	}
+	if !p.peekTokenIs2(token.IDENT) {
+		t.Fatalf("peek2Token is not IDENT. got=%q", p.peek2Token.Type)
+	}
+
+	// the two-token window must stay consistent all the way to EOF
+	expected := []token.TokenType{token.LBRACE, token.IDENT, token.RBRACE, token.SEMICOLON, token.EOF, token.EOF}
+	for i, tt := range expected {
+		p.nextToken()
+		if !p.currTokenIs(tt) {
+			t.Fatalf("tokens[%d] wrong. want=%q, got=%q", i, tt, p.currToken.Type)
+		}
+		if i+2 < len(expected) && !p.peekTokenIs2(expected[i+2]) {
+			t.Fatalf("tokens[%d] peek2 wrong. want=%q, got=%q", i, expected[i+2], p.peek2Token.Type)
+		}
+	}
+}
+
+func TestPrecedenceLevels(t *testing.T) {
+	expected := []struct {
+		name      string
+		operators string
+	}{
+		{"EQUALS", "!= =="},
+		{"LESSGREATER", "< >"},
+		{"SUM", "+ -"},
+		{"PRODUCT", "* /"},
+		{"PREFIX", "! -"},
+		{"CALL", "("},
+	}
+
+	levels := PrecedenceLevels()
+	if len(levels) != len(expected) {
+		t.Fatalf("wrong number of levels. want=%d, got=%d (%+v)", len(expected), len(levels), levels)
+	}
+
+	for i, tt := range expected {
+		var operators []string
+		for _, op := range levels[i].Operators {
+			operators = append(operators, string(op))
+		}
+
+		if levels[i].Name != tt.name || strings.Join(operators, " ") != tt.operators {
+			t.Errorf("levels[%d] wrong. want=%s %q, got=%s %q", i, tt.name, tt.operators, levels[i].Name, operators)
+		}
+		if i > 0 && levels[i].Precedence <= levels[i-1].Precedence {
+			t.Errorf("levels[%d] (%s) doesn't bind tighter than levels[%d] (%s)", i, levels[i].Name, i-1, levels[i-1].Name)
+		}
+	}
+}
+
+func TestDebugTree(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"1 + 2 * 3",
+			`+ [SUM]
+  1
+  * [PRODUCT]
+    2
+    3
+`,
+		},
+		{
+			"(1 + 2) * 3",
+			`* [PRODUCT]
+  + [SUM]
+    1
+    2
+  3
+`,
+		},
+		{
+			"-a(b) == !c < d",
+			`== [EQUALS]
+  - [PREFIX]
+    call [CALL]
+      a
+      b
+  < [LESSGREATER]
+    ! [PREFIX]
+      c
+    d
+`,
+		},
+		{"x", "x\n"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if got := DebugTree(stmt.Expression); got != tt.expected {
+			t.Errorf("wrong tree for %q.\nexpected:\n%s\ngot:\n%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestStrictSemicolons(t *testing.T) {
+	tests := []struct {
+		input  string
+		errors []string
+	}{
+		{"5;", nil},
+		{"5", []string{"expected next token to be ;, got EOF instead"}},
+		{"let x = 5; return x;", nil},
+		{"let x = 5\nreturn x;", []string{"expected next token to be ;, got RETURN instead"}},
+		{"let a, b = 1, 2; a, b = b, a;", nil},
+		{"a, b = b, a", []string{"expected next token to be ;, got EOF instead"}},
+		{"let f = fn(x) { return x; };", nil},
+		{"let f = fn(x) { x };", []string{"expected next token to be ;, got } instead"}},
+		{"if (x) { 1; };", nil},
+		{"if (x) { 1; }", []string{"expected next token to be ;, got EOF instead"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input), WithStrictSemicolons())
+		p.ParseProgram()
+
+		if strings.Join(p.Errors(), "\n") != strings.Join(tt.errors, "\n") {
+			t.Errorf("wrong errors for %q in strict mode.\nwant=%q\ngot= %q", tt.input, tt.errors, p.Errors())
+		}
+
+		// without the option, the semicolons are all optional
+		p = New(lexer.New(tt.input))
+		p.ParseProgram()
+		checkParserErrors(t, p)
+	}
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"monkey/token"
+	"sort"
+)
+
+// precedenceNames maps each precedence level back to the name of its constant, for DebugTree() and
+// PrecedenceLevels().
+
+var precedenceNames = map[int]string{
+	LOWEST:      "LOWEST",
+	EQUALS:      "EQUALS",
+	LESSGREATER: "LESSGREATER",
+	SUM:         "SUM",
+	PRODUCT:     "PRODUCT",
+	PREFIX:      "PREFIX",
+	CALL:        "CALL",
+}
+
+// prefixOperators are the tokens parsed by parsePrefixExpression(), at PREFIX precedence.
+
+var prefixOperators = []token.TokenType{token.BANG, token.MINUS}
+
+// PrecedenceLevel is one row of the operator precedence table: a level and the operator tokens parsed at it.
+
+type PrecedenceLevel struct {
+	Precedence int
+	Name       string
+	Operators  []token.TokenType
+}
+
+// PrecedenceLevels() returns the operator precedence table, from the loosest-binding level to the tightest, with the
+// operators of each level in sorted order. It's built from the same tables the parser uses, so documentation generated
+// from it can't drift from what the parser does. Levels without any operators, like LOWEST, are left out.
+
+func PrecedenceLevels() []PrecedenceLevel {
+	operators := map[int][]token.TokenType{PREFIX: prefixOperators}
+	for tokenType, precedence := range precedences {
+		operators[precedence] = append(operators[precedence], tokenType)
+	}
+
+	levels := []PrecedenceLevel{}
+	for precedence, tokenTypes := range operators {
+		sorted := append([]token.TokenType{}, tokenTypes...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		levels = append(levels, PrecedenceLevel{
+			Precedence: precedence,
+			Name:       precedenceNames[precedence],
+			Operators:  sorted,
+		})
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Precedence < levels[j].Precedence })
+
+	return levels
+}
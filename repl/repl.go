@@ -4,10 +4,13 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"monkey/ast"
 	"monkey/evaluator"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"os"
+	"strings"
 )
 
 const PROMPT = ">> "
@@ -24,6 +27,11 @@ func Start(in io.Reader, out io.Writer) {
 		}
 
 		line := scanner.Text()
+		if strings.HasPrefix(line, ":") {
+			env = runCommand(out, line, env)
+			continue
+		}
+
 		l := lexer.New(line)
 		p := parser.New(l)
 
@@ -41,6 +49,66 @@ func Start(in io.Reader, out io.Writer) {
 	}
 }
 
+// runCommand() handles the REPL's own commands, which start with a colon, e.g. `:unset x`. It returns the environment
+// the session should continue with, which is a fresh one after `:reset`.
+
+func runCommand(out io.Writer, line string, env *object.Environment) *object.Environment {
+	fields := strings.Fields(line)
+
+	switch fields[0] {
+	case ":unset":
+		if len(fields) != 2 {
+			io.WriteString(out, "usage: :unset <name>\n")
+			break
+		}
+		if !env.Delete(fields[1]) {
+			io.WriteString(out, fields[1]+" is not bound\n")
+		}
+	case ":save":
+		if len(fields) != 2 {
+			io.WriteString(out, "usage: :save <path>\n")
+			break
+		}
+		if err := os.WriteFile(fields[1], []byte(sessionSource(env)), 0644); err != nil {
+			io.WriteString(out, "could not save session: "+err.Error()+"\n")
+		}
+	case ":builtins":
+		io.WriteString(out, strings.Join(evaluator.BuiltinNames(), " ")+"\n")
+	case ":reset":
+		// builtins don't live in the environment, so they survive the reset
+		return object.NewEnvironment()
+	default:
+		io.WriteString(out, "unknown command: "+fields[0]+"\n")
+	}
+
+	return env
+}
+
+// sessionSource() reconstructs the top-level bindings of env as Monkey source, one `let` per line, so that a saved
+// session can be loaded back in. Functions are printed with ast.Source(), since Inspect() drops the braces of their
+// blocks. Values with no source form (builtins, null, errors) are skipped, and so are closures: only a function defined
+// directly in env can be restored from its source, since any other one refers to bindings that aren't saved.
+
+func sessionSource(env *object.Environment) string {
+	var out strings.Builder
+
+	for _, name := range env.Names() {
+		val, _ := env.Get(name)
+		switch val := val.(type) {
+		case *object.Integer, *object.Boolean:
+			fmt.Fprintf(&out, "let %s = %s;\n", name, val.Inspect())
+		case *object.Function:
+			if val.Env != env {
+				continue
+			}
+			fn := &ast.FunctionLiteral{Parameters: val.Parameters, Body: val.Body}
+			fmt.Fprintf(&out, "let %s = %s;\n", name, ast.Source(fn))
+		}
+	}
+
+	return out.String()
+}
+
 const MONKEY_FACE = `            __,__
    .--.  .-"     "-.  .--.
   / .. \/  .-. .-.  \/ .. \
@@ -0,0 +1,119 @@
+package repl
+
+import (
+	"bytes"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReset(t *testing.T) {
+	input := `let x = 5;
+x
+:reset
+x
+abs(-1)
+`
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	expected := PROMPT + PROMPT + "5\n" + PROMPT + PROMPT + "ERROR: identifier not found: x\n" + PROMPT + "1\n" + PROMPT
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=     %q", expected, out.String())
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	var out bytes.Buffer
+	Start(strings.NewReader(":builtins\n"), &out)
+
+	expected := PROMPT + strings.Join(evaluator.BuiltinNames(), " ") + "\n" + PROMPT
+	if out.String() != expected {
+		t.Errorf("wrong output.\nexpected=%q\ngot=     %q", expected, out.String())
+	}
+	if !strings.Contains(out.String(), "abs gcd max memoize min pow puts") {
+		t.Errorf("builtins missing or out of order. got=%q", out.String())
+	}
+}
+
+func TestSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.monkey")
+	input := `let x = 5;
+let add = fn(a, b) { let sum = a + b; sum };
+let ok = x > 1;
+let nothing = if (false) { 1 };
+:save ` + path + `
+`
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read saved session: %s", err)
+	}
+
+	l := lexer.New(string(saved))
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("saved session does not parse: %v\n%s", p.Errors(), saved)
+	}
+
+	expected := "let add = fn(a, b)let sum = (a + b);sum;let ok = true;let x = 5;"
+	if program.String() != expected {
+		t.Errorf("wrong saved definitions.\nexpected=%q\ngot=     %q", expected, program.String())
+	}
+}
+
+func TestSaveAndRerun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.monkey")
+	input := `let f = fn(n) { if (n > 1) { return n * 10; } else { let m = -n; m } };
+let g = fn(n) { if (n) { 1 } };
+:save ` + path + `
+`
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read saved session: %s", err)
+	}
+
+	// replaying the saved session line by line has to restore working functions
+	var replayed bytes.Buffer
+	Start(strings.NewReader(string(saved)+"f(5)\nf(1)\ng(true)\n"), &replayed)
+
+	expected := strings.Repeat(PROMPT, 3) + "50\n" + PROMPT + "-1\n" + PROMPT + "1\n" + PROMPT
+	if replayed.String() != expected {
+		t.Errorf("saved session doesn't run.\nsaved=\n%s\nexpected=%q\ngot=     %q", saved, expected, replayed.String())
+	}
+}
+
+func TestSaveSkipsClosures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.monkey")
+	input := `let mk = fn(n) { fn(x) { x + n } };
+let add2 = mk(2);
+:save ` + path + `
+`
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read saved session: %s", err)
+	}
+
+	// add2 needs the n it closed over, which isn't in the session, so it can't be saved
+	expected := "let mk = fn(n) { fn(x) { (x + n); }; };\n"
+	if string(saved) != expected {
+		t.Errorf("wrong saved session.\nexpected=%q\ngot=     %q", expected, saved)
+	}
+}
@@ -0,0 +1,91 @@
+package resolver
+
+import (
+	"fmt"
+	"monkey/ast"
+)
+
+// Resolve() walks the program and builds the symbol table for every scope in it: let statements define names in the
+// current scope, function literals open a new scope in which their parameters are defined first, and let-in
+// expressions open one holding just their name.
+
+func Resolve(program *ast.Program) *SymbolTable {
+	r := &resolver{}
+	global := NewSymbolTable()
+
+	for _, s := range program.Statements {
+		r.resolveNode(s, global)
+	}
+
+	return global
+}
+
+type resolver struct {
+	functions int // number of function scopes seen so far, used to name them
+	lets      int // same for let-in scopes
+}
+
+func (r *resolver) resolveNode(node ast.Node, table *SymbolTable) {
+	switch node := node.(type) {
+
+	// Statements
+	case *ast.LetStatement:
+		// the value is resolved first, so `let x = x;` doesn't see its own declaration
+		r.resolveNode(node.Value, table)
+		table.Define(node.Name.Value)
+	case *ast.MultiLetStatement:
+		for _, v := range node.Values {
+			r.resolveNode(v, table)
+		}
+		for _, n := range node.Names {
+			table.Define(n.Value)
+		}
+	case *ast.MultiAssignStatement:
+		for _, v := range node.Values {
+			r.resolveNode(v, table)
+		}
+	case *ast.ReturnStatement:
+		r.resolveNode(node.ReturnValue, table)
+	case *ast.ExpressionStatement:
+		r.resolveNode(node.Expression, table)
+	case *ast.BlockStatement:
+		for _, s := range node.Statements {
+			r.resolveNode(s, table)
+		}
+
+	// Expressions
+	case *ast.PrefixExpression:
+		r.resolveNode(node.Right, table)
+	case *ast.InfixExpression:
+		r.resolveNode(node.Left, table)
+		r.resolveNode(node.Right, table)
+	case *ast.IfExpression:
+		r.resolveNode(node.Condition, table)
+		r.resolveNode(node.Consequence, table)
+		if node.Alternative != nil {
+			r.resolveNode(node.Alternative, table)
+		}
+	case *ast.FunctionLiteral:
+		r.functions++
+		inner := NewEnclosedSymbolTable(table, fmt.Sprintf("fn#%d", r.functions))
+		for _, p := range node.Parameters {
+			inner.Define(p.Value)
+		}
+		r.resolveNode(node.Body, inner)
+	case *ast.CallExpression:
+		r.resolveNode(node.Function, table)
+		for _, a := range node.Arguments {
+			r.resolveNode(a, table)
+		}
+	case *ast.DecoratorExpression:
+		r.resolveNode(node.Decorator, table)
+		r.resolveNode(node.Function, table)
+	case *ast.LetInExpression:
+		// the name is only visible in the body, so like a function it gets a scope of its own
+		r.resolveNode(node.Value, table)
+		r.lets++
+		inner := NewEnclosedSymbolTable(table, fmt.Sprintf("let#%d", r.lets))
+		inner.Define(node.Name.Value)
+		r.resolveNode(node.Body, inner)
+	}
+}
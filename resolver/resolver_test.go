@@ -0,0 +1,111 @@
+package resolver
+
+import (
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	input := `
+let a = 1;
+let add = fn(x, y) {
+	let sum = x + y;
+	if (sum > a) {
+		let big = true;
+	} else {
+		let small = fn(z) { z };
+	}
+	sum;
+};
+let b = add(a, 2);
+let a = 3;
+`
+
+	expected := `global:
+  0 GLOBAL a
+  1 GLOBAL add
+  2 GLOBAL b
+fn#1 (in global):
+  0 LOCAL x
+  1 LOCAL y
+  2 LOCAL sum
+  3 LOCAL big
+  4 LOCAL small
+fn#2 (in fn#1):
+  0 LOCAL z
+`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	table := Resolve(program)
+
+	if table.String() != expected {
+		t.Errorf("symbol table dump wrong.\nexpected=\n%s\ngot=\n%s", expected, table.String())
+	}
+}
+
+func TestResolveLetIn(t *testing.T) {
+	input := "let a = let b = 1 in let c = b in b + c;"
+
+	expected := `global:
+  0 GLOBAL a
+let#1 (in global):
+  0 LOCAL b
+let#2 (in let#1):
+  0 LOCAL c
+`
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	if table := Resolve(program); table.String() != expected {
+		t.Errorf("symbol table dump wrong.\nexpected=\n%s\ngot=\n%s", expected, table.String())
+	}
+}
+
+func TestResolveSymbol(t *testing.T) {
+	global := NewSymbolTable()
+	a := global.Define("a")
+
+	local := NewEnclosedSymbolTable(global, "fn#1")
+	b := local.Define("b")
+
+	tests := []struct {
+		table    *SymbolTable
+		name     string
+		expected Symbol
+	}{
+		{global, "a", a},
+		{local, "a", Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{local, "b", Symbol{Name: "b", Scope: LocalScope, Index: 0}},
+	}
+
+	for _, tt := range tests {
+		symbol, ok := tt.table.Resolve(tt.name)
+		if !ok {
+			t.Errorf("name %s not resolvable", tt.name)
+			continue
+		}
+		if symbol != tt.expected {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", tt.name, tt.expected, symbol)
+		}
+	}
+
+	if b.Scope != LocalScope {
+		t.Errorf("b.Scope not %s. got=%s", LocalScope, b.Scope)
+	}
+
+	if _, ok := global.Resolve("b"); ok {
+		t.Errorf("local name b resolvable from the global scope")
+	}
+}
@@ -0,0 +1,106 @@
+package resolver
+
+import (
+	"bytes"
+	"fmt"
+)
+
+type SymbolScope string
+
+const (
+	GlobalScope SymbolScope = "GLOBAL"
+	LocalScope  SymbolScope = "LOCAL"
+)
+
+// Symbol holds everything we know about a declared name: the name itself, the scope it was declared in and its index
+// within that scope (the order in which it was defined).
+
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+// SymbolTable maps names to symbols for a single scope. The global scope is the root of the tree, every function
+// literal and let-in expression gets its own enclosed table. Blocks (the consequence/alternative of an if expression)
+// don't introduce a new scope in Monkey, so names declared in them end up in the table of the enclosing function or
+// the global table.
+
+type SymbolTable struct {
+	Name  string // "global" for the root table, "fn#N"/"let#N" for the N-th function/let-in scope in source order
+	Outer *SymbolTable
+	Inner []*SymbolTable
+
+	store map[string]Symbol
+	names []string // names in definition order, so String() is deterministic
+}
+
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{Name: "global", store: make(map[string]Symbol)}
+}
+
+func NewEnclosedSymbolTable(outer *SymbolTable, name string) *SymbolTable {
+	s := &SymbolTable{Name: name, Outer: outer, store: make(map[string]Symbol)}
+	outer.Inner = append(outer.Inner, s)
+	return s
+}
+
+// Define() declares name in this scope. Redefining a name that already exists in the same scope keeps its original
+// index, just like `let x = 1; let x = 2;` overwrites the same binding in the evaluator's environment.
+
+func (s *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := s.store[name]; ok {
+		return symbol
+	}
+
+	symbol := Symbol{Name: name, Index: len(s.names)}
+	if s.Outer == nil {
+		symbol.Scope = GlobalScope
+	} else {
+		symbol.Scope = LocalScope
+	}
+
+	s.store[name] = symbol
+	s.names = append(s.names, name)
+	return symbol
+}
+
+// Resolve() looks name up in this scope and then in the enclosing ones.
+
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	symbol, ok := s.store[name]
+	if !ok && s.Outer != nil {
+		return s.Outer.Resolve(name)
+	}
+	return symbol, ok
+}
+
+// String() dumps the table and all of its inner tables, one group of entries per scope, e.g.
+//
+//	global:
+//	  0 GLOBAL add
+//	fn#1 (in global):
+//	  0 LOCAL x
+
+func (s *SymbolTable) String() string {
+	var out bytes.Buffer
+	s.writeTo(&out)
+	return out.String()
+}
+
+func (s *SymbolTable) writeTo(out *bytes.Buffer) {
+	if s.Outer == nil {
+		out.WriteString(s.Name + ":\n")
+	} else {
+		out.WriteString(fmt.Sprintf("%s (in %s):\n", s.Name, s.Outer.Name))
+	}
+
+	for _, name := range s.names {
+		symbol := s.store[name]
+		out.WriteString(fmt.Sprintf("  %d %s %s\n", symbol.Index, symbol.Scope, symbol.Name))
+	}
+
+	for _, inner := range s.Inner {
+		inner.writeTo(out)
+	}
+}
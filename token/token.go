@@ -1,10 +1,20 @@
 package token
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 type TokenType string
 
+// Token is a single token with the position of its first character. Line and Column start at 1; tokens built by hand
+// rather than by the lexer leave them at 0.
+
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type    TokenType `json:"type"`
+	Literal string    `json:"literal"`
+	Line    int       `json:"line"`
+	Column  int       `json:"column"`
 }
 
 const (
@@ -13,8 +23,10 @@ const (
 
 	// Identifiers + literals
 
-	IDENT = "IDENT" // add, foobar, x, y, ...
-	INT   = "INT"   // 1234524
+	IDENT  = "IDENT"  // add, foobar, x, y, ...
+	INT    = "INT"    // 1234524
+	FLOAT  = "FLOAT"  // 3.14
+	STRING = "STRING" // "foo bar"
 
 	// Operators
 
@@ -24,22 +36,47 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
+	MODULO   = "%"
+
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
 
-	LT = "<"
-	GT = ">"
+	LT    = "<"
+	GT    = ">"
+	LT_EQ = "<="
+	GT_EQ = ">="
 
 	EQ     = "=="
 	NOT_EQ = "!="
 
+	BIT_AND = "&"
+	BIT_OR  = "|"
+	BIT_XOR = "^"
+	BIT_NOT = "~"
+
+	AND = "&&"
+	OR  = "||"
+
+	SHL = "<<"
+	SHR = ">>"
+
 	// Delimiters
 
 	COMMA     = ","
 	SEMICOLON = ";"
-
-	LPAREN = "("
-	RPAREN = ")"
-	LBRACE = "{"
-	RBRACE = "}"
+	NEWLINE   = "NEWLINE" // only with lexer.WithNewlineTokens()
+	COLON     = ":"
+	DOT       = "."
+	AT        = "@"
+
+	LPAREN   = "("
+	RPAREN   = ")"
+	LBRACE   = "{"
+	RBRACE   = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
 
 	// Keywords
 
@@ -50,6 +87,7 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	IN       = "IN"
 )
 
 var keywords = map[string]TokenType{
@@ -60,12 +98,14 @@ var keywords = map[string]TokenType{
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"in":     IN,
 }
 
 // LookupIdent() checks the keywords table to see whether the given identifier is
 // in fact a keyword. If it is, it returns the keyword's TokenType constant. If
 // it isn't, it assumes we're dealing with a regular identifier and returns the
-// token.IDENT constant.
+// token.IDENT constant. Any string is accepted: the empty string and strings
+// that couldn't be lexed as an identifier at all are simply not keywords.
 
 func LookupIdent(ident string) TokenType {
 	if tok, ok := keywords[ident]; ok {
@@ -73,3 +113,23 @@ func LookupIdent(ident string) TokenType {
 	}
 	return IDENT // The TokenType for all user-defined identifiers
 }
+
+// LookupIdentCaseInsensitive() is LookupIdent() for the case-insensitive keyword mode: `LET`, `Let` and `let` are all
+// the LET keyword. Only the lookup is case-insensitive, the caller keeps the identifier as it was written.
+
+func LookupIdentCaseInsensitive(ident string) TokenType {
+	return LookupIdent(strings.ToLower(ident))
+}
+
+// ToJSON() serializes a token stream for consumption by external tools, e.g.
+//
+//	[{"type":"LET","literal":"let","line":1,"column":1},{"type":"IDENT","literal":"x","line":1,"column":5}]
+//
+// TokenType is a string, so the type is written as its string value.
+
+func ToJSON(tokens []Token) ([]byte, error) {
+	if tokens == nil {
+		tokens = []Token{} // encode an empty stream as [] rather than null
+	}
+	return json.Marshal(tokens)
+}
@@ -0,0 +1,74 @@
+package token
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookupIdent(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected TokenType
+	}{
+		{"", IDENT},
+		{"let", LET},
+		{"fn", FUNCTION},
+		{"return", RETURN},
+		{"LET", IDENT},
+		{"lets", IDENT},
+		{"let ", IDENT},
+		{"çay", IDENT},
+		{"変数", IDENT},
+		{"\x00", IDENT},
+		{"\xff", IDENT},
+	}
+
+	for _, tt := range tests {
+		if got := LookupIdent(tt.input); got != tt.expected {
+			t.Errorf("LookupIdent(%q) wrong. want=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func FuzzLookupIdent(f *testing.F) {
+	for _, seed := range []string{"", "let", "LeT", "fn", "x", "çay", "\x00", "\xff\xfe"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, ident string) {
+		got := LookupIdent(ident)
+		if _, ok := keywords[ident]; !ok && got != IDENT {
+			t.Errorf("LookupIdent(%q) = %q, want IDENT for a non-keyword", ident, got)
+		}
+		if got := LookupIdentCaseInsensitive(ident); got != LookupIdent(strings.ToLower(ident)) {
+			t.Errorf("LookupIdentCaseInsensitive(%q) = %q, want the lookup of the lowercased identifier", ident, got)
+		}
+	})
+}
+
+func TestToJSON(t *testing.T) {
+	tokens := []Token{
+		{Type: LET, Literal: "let", Line: 1, Column: 1},
+		{Type: IDENT, Literal: "x", Line: 1, Column: 5},
+		{Type: ASSIGN, Literal: "=", Line: 1, Column: 7},
+		{Type: INT, Literal: "5", Line: 1, Column: 9},
+		{Type: SEMICOLON, Literal: ";", Line: 1, Column: 10},
+		{Type: EOF, Literal: "", Line: 1, Column: 11},
+	}
+
+	expected := `[{"type":"LET","literal":"let","line":1,"column":1},{"type":"IDENT","literal":"x","line":1,"column":5},` +
+		`{"type":"=","literal":"=","line":1,"column":7},{"type":"INT","literal":"5","line":1,"column":9},` +
+		`{"type":";","literal":";","line":1,"column":10},{"type":"EOF","literal":"","line":1,"column":11}]`
+
+	encoded, err := ToJSON(tokens)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(encoded) != expected {
+		t.Errorf("wrong JSON.\nexpected=%s\ngot=     %s", expected, encoded)
+	}
+
+	if encoded, _ := ToJSON(nil); string(encoded) != "[]" {
+		t.Errorf("empty token stream not encoded as []. got=%s", encoded)
+	}
+}